@@ -16,6 +16,72 @@ command.
 
 The "Type" can also be "local" and the "Repository" local folder.
 The "TempFolder" is were the posts will be cloned for generation.
+
+With the "archive" type the "Repository" is the path or the http(s) url of a
+.zip, .tar or .tar.gz file, which is extracted into the "TempFolder".
+"StripComponents": 1
+removes the leading folder of the entries, e.g. "blog-1.0/". Entries that
+would end up outside of the "TempFolder", like "../post.md" or symlinks to
+absolute paths, stop the extraction.
+
+Other datasource types can be registered by programs that embed blog-gen.
+Their settings go in the "Options" field of "DataSource".
+
+The following optional fields of "DataSource" select what is fetched.
+"Ref": "main",
+"Subdirectory": "posts"
+
+The "Ref" is the branch, tag or commit of a "git" repository to check out.
+By default the default branch of the repository is used. When "Subdirectory"
+is set only that folder of the repository is used as the posts folder. A
+"git" repository is then checked out in a folder next to the "TempFolder",
+with the "_checkout" suffix.
+
+Private "git" repositories need the optional "Auth" field of "DataSource".
+"Auth": {
+    "SSHKey": "/home/user/.ssh/blog_deploy_key",
+    "TokenEnv": "BLOG_POSTS_TOKEN",
+    "Username": "x-access-token",
+    "CredentialHelper": "store"
+}
+
+"SSHKey" is the private key used for ssh urls. "TokenEnv" is the name of the
+environment variable that holds a token for https urls, sent with the
+"Username" (default "x-access-token"). "CredentialHelper" is a git credential
+helper used for https urls instead of a token. Set only one of them. The
+secrets are passed to git only for the duration of the command, they are
+never written to the repository configuration or to the output.
+
+An existing clone of the same "git" repository is updated with a fetch
+instead of being cloned again. It is cloned from scratch only when the
+"Repository" changes or the existing clone is broken. A failed fetch, e.g.
+of an unreachable remote or an unknown "Ref", keeps the existing clone.
+
+Posts can be merged from many datasources with "DataSources" instead of
+"DataSource". Every entry has the fields of "DataSource" and is fetched in its
+own folder inside the "TempFolder", named after its "Name" or "source1",
+"source2" and so on.
+
+"DataSources": [
+    {
+        "Name": "blog",
+        "Type": "git",
+        "Repository": "https://github.com/RomanosTrechlis/blog.git"
+    },
+    {
+        "Name": "notes",
+        "Type": "local",
+        "Repository": "./notes",
+        "Prefix": "notes",
+        "Tags": ["notes"],
+        "Categories": ["Notes"]
+    }
+]
+
+The "Prefix" is prepended to the URL of the posts of the entry, e.g.
+"/notes/my-note/". "Tags" and "Categories" are added to every post of the
+entry. Posts of all entries are sorted together by date, and two posts that
+end up with the same URL are an error.
 `
 
 	getThemeShortHelp = `Downloads theme from given datasource`
@@ -33,6 +99,13 @@ command.
 The "Type" can also be "local" and the "Repository" a local folder.
 The "ThemeFolder" is were the static pages of the theme will be
 cloned for use in the blog generation phase.
+
+The "Theme" accepts the same optional "Ref" and "Subdirectory" fields as the
+"DataSource", e.g. to pin the theme to a release tag:
+"Ref": "v1.2.0"
+
+Private theme repositories use the same optional "Auth" field as the
+"DataSource". See "posts" for more information.
 `
 
 	generateShortHelp = `Generates blog from existing resources`
@@ -68,19 +141,150 @@ The following snippet specifies the static pages and other artifacts like .css
 
 Finally, the following fields contain information about the site.
 "Author": "Romanos Trechlis",
-"BlogURL": "romanostrechlis.github.io",
+"BlogURL": "https://romanostrechlis.github.io",
 "BlogLanguage": "en-us",
 "BlogDescription": "This is my personal blog.",
 "DateFormat": "2006-01-02 15:04:05",
 
+The "BlogURL" can include a path, e.g. "https://example.com/blog/", when the
+blog is not hosted at the root of the domain. Every generated link starts
+with that path. Themes should create their links with the template functions
+relURL and absURL instead of writing them by hand:
+<link rel="stylesheet" href="{{relURL "/style.css"}}">
+<a href="{{absURL "/index.xml"}}">RSS</a>
+
+The urls of the posts, the tags and the categories follow the patterns of
+"Permalinks". A pattern can use the tokens :year, :month, :day, :slug,
+:section and :category, and must contain :slug. The section is the "Prefix"
+of the datasource of the post and the category is its first one.
+"Permalinks": {
+    "Post": "/:year/:month/:slug/",
+    "Tag": "/tags/:slug/",
+    "Category": "/categories/:slug/"
+},
+"UglyURLs": false
+
+Patterns that end with "/" are written as an index.html in a folder. When
+"UglyURLs" is true they are written as .html files instead, e.g.
+/2020/01/my-post.html, and the images of the post are linked from the
+my-post folder next to it.
+
+The pages of all the tags and all the categories are written in the folder
+of their "Permalinks", e.g. /topics/ for "/topics/:slug/". Patterns at the
+root of the site keep them in /tags/ and /categories/.
+
+Every post is a folder with a post.md and a meta.yml. Instead of the
+meta.yml the same fields can be given as front matter at the top of post.md,
+in YAML between "---" lines or in TOML between "+++" lines:
+
++++
+title = "My post"
+date = "2020-01-02 15:04:05"
+tags = ["go", "web"]
++++
+
+The front matter is removed before the markdown is rendered. When a post has
+both a meta.yml and front matter, a field may be set in both only with the
+same value.
+
+Posts without images or artifacts can also be a single markdown file with
+front matter, e.g. "some-slug.md", next to the post folders. The file name
+without the extension is the name of the post. Markdown files without front
+matter, like a README.md, are skipped.
+
+Posts can control when they are published with the following fields of
+their meta.yml. Dates use the "DateFormat" of config.json.
+draft: true
+publishdate: 2020-01-02 15:04:05
+expirydate: 2021-01-02 15:04:05
+
+Drafts, posts with a publish date in the future and posts with an expiry date
+in the past are left out of every generated page, listing, tag, category,
+sitemap and RSS feed. Use the --drafts, --future and --expired flags to
+include them for a local preview.
+
+The posts are read and the pages are generated in parallel, one at a time for
+every CPU. The --jobs (-j) flag sets how many run at the same time. Every post
+that cannot be read is reported at once. When a page fails the pages that have
+not started are skipped, and every failure is reported with its post or
+page. Ctrl-C stops the generation after the pages that are being written.
+
+The blog is generated in a hidden folder next to the "DestFolder", e.g.
+.public.staging, which replaces the "DestFolder" only when every page is
+generated. A failed or interrupted generation leaves the previous blog in
+place. The builds are kept in .public.builds and the "DestFolder" is a
+symlink to the current one, which is replaced with a single rename, so the
+server always finds a complete blog. With "KeepBuilds" the previous builds
+are kept, see "rollback".
+
+Posts and pages whose inputs did not change since the previous build are
+not generated again, their files are reused. The hashes of the inputs are
+kept in a hidden file next to the "DestFolder", e.g. .public.cache.json.
+A change in the theme or in config.json generates every page. The --force
+flag generates every page regardless.
+
+The generation ends with a summary of the pages, the posts, the reused
+pages, the warnings and the durations. The global --verbose (-v) flag shows
+the progress of every page, --quiet (-q) only the warnings and the errors,
+and --log-format json writes the messages as json objects.
+
 To see a config.json example run: blog-generator json-example
+`
+
+	newPostShortHelp = `Creates the folder of a new post`
+	newPostLongHelp  = `
+Creates the folder of a new post inside the local datasource of the
+config.json file. The folder name is created from the title of the post.
+
+new -t "My new post" -g "go, web" -c programming
+
+The folder contains a meta.yml with the title, the tags, the categories and
+the current date in the "DateFormat" of config.json, and an empty post.md.
+Images and other artifacts can be added later in the "images" and
+"artifacts" folders of the post.
+
+The "Type" of the "DataSource" must be "local". When "DataSources" is used the
+post is created in the first local one, or in the one named by the -source
+flag.
+
+new -t "My note" -o notes
+`
+
+	uploadShortHelp = `Uploads the generated blog to the configured endpoint`
+	uploadLongHelp  = `
+Uploads the generated blog to the endpoint given inside the config.json file.
+Before this command runs the generate command should run.
+
+The following part of config.json controls the behavior of "upload"
+command.
+
+"Upload": {
+    "Type": "git",
+    "URL": "https://github.com/RomanosTrechlis/romanostrechlis.github.io.git",
+    "Username": "RomanosTrechlis",
+    "Password": "",
+    "Branch": "master",
+    "CommitMessage": "Update site from {{.Commit}} built at {{.BuildTime}}"
+},
+"DestFolder": "./public"
+
+The "Type" selects the endpoint. Currently only "git" is supported.
+The "DestFolder" is the folder with the generated blog that will be pushed.
+
+The git endpoint keeps a clone of the "Branch" (default "master") next to
+the "DestFolder" and commits only the files that changed since the last
+upload. The "CommitMessage" is a template where {{.Commit}} is the commit
+of the posts repository and {{.BuildTime}} the time the blog was generated.
+
+Use the --dry-run flag to list the files that would be uploaded without
+pushing anything.
 `
 
 	jsonExampleShortHelp = `Run: "blog-generator json-exmple" to see a config.json example`
 	jsonExampleLongHelp  = `
 {
   "Author": "Romanos Trechlis",
-  "BlogURL": "romanostrechlis.github.io",
+  "BlogURL": "https://romanostrechlis.github.io",
   "BlogLanguage": "en-us",
   "BlogDescription": "This is my personal blog.",
   "DateFormat": "2006-01-02 15:04:05",
@@ -133,9 +337,38 @@ To see a config.json example run: blog-generator json-example
   	"Type": "git",
     "URL": "https://github.com/RomanosTrechlis/romanostrechlis.github.io.git",
     "Username": "RomanosTrechlis",
-    "Password": ""
+    "Password": "",
+    "Branch": "master",
+    "CommitMessage": "Update site from {{.Commit}} built at {{.BuildTime}}"
   }
 }
+`
+
+	rollbackShortHelp = `Restores the previous build of the blog`
+	rollbackLongHelp  = `
+Points the "DestFolder" to the newest of the previous builds kept by
+generate. The replaced build is removed, so running rollback again goes one
+more build back.
+
+The number of previous builds that are kept is set in config.json:
+"KeepBuilds": 3
+
+The builds are kept in a hidden folder next to the "DestFolder", e.g.
+.public.builds for ./public. Without "KeepBuilds" there is nothing to restore.
+`
+
+	watchShortHelp = `Rebuilds the blog when posts, theme or configuration change`
+	watchLongHelp  = `
+Generates the blog and then watches for changes in the posts, the theme and
+the config.json file. Every change regenerates the blog. Rapid successive
+changes result in a single rebuild.
+
+For "local" datasources the "Repository" folders are watched and copied again
+before every rebuild. For "git" datasources the "TempFolder" and the
+"ThemeFolder" are watched, so the fetched files can be edited in place.
+
+A failed rebuild, for example because of a broken template or meta.yml, is
+reported and the watching continues.
 `
 
 	runShortHelp = `Runs a web server for the generated blog`
@@ -143,11 +376,27 @@ To see a config.json example run: blog-generator json-example
 Runs a web server for the generated blog.
 
 The default port for the server is 8080.
+
+With the --watch flag the blog is rebuilt when posts, theme or configuration
+change, while the web server keeps running. See "watch" for more information.
+
+With the --livereload flag the open pages reload themselves after every
+rebuild. A small script is added to the served HTML pages, the generated
+files are not changed. The --livereload flag implies --watch.
+
+The --drafts, --future and --expired flags include the posts that are not
+published yet, or anymore, as in the "generate" command. The blog is
+generated once with these posts before it is served, and again on every
+change only with --watch or --livereload.
 `
 
 	execAllShortHelp = `Sequential execution of all commands`
 	execAllLongHelp  = `Sequential execution of all commands.
 
 Downloads posts, and theme, then generates the blog and runs a web server.
+With the --upload flag the generated blog is also uploaded to the configured
+endpoint before the web server starts.
+
+The --watch and --livereload flags work as in the "server" command.
 `
 )
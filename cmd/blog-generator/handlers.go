@@ -1,132 +1,415 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/RomanosTrechlis/blog-gen/config"
 	"github.com/RomanosTrechlis/blog-gen/datasource"
+	"github.com/RomanosTrechlis/blog-gen/endpoint"
 	"github.com/RomanosTrechlis/blog-gen/generator"
 	"github.com/RomanosTrechlis/blog-gen/util/fs"
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
+	"github.com/RomanosTrechlis/blog-gen/util/url"
 	"github.com/RomanosTrechlis/go-icls/cli"
 )
 
-func getPostHandler(siteInfo config.SiteInformation) func(flags map[string]string) error {
+func getPostHandler(siteInfo config.SiteInformation, log logger.Logger) func(flags map[string]string) error {
 	return func(flags map[string]string) error {
-		ds, err := datasource.New(siteInfo.DataSource.Type)
+		return fetchPosts(siteInfo, log)
+	}
+}
+
+func getThemeHandler(siteInfo config.SiteInformation, log logger.Logger) func(flags map[string]string) error {
+	return func(flags map[string]string) error {
+		return fetchTheme(siteInfo, log)
+	}
+}
+
+func getGenerateHandler(c *cli.CLI, siteInfo config.SiteInformation, log logger.Logger) func(flags map[string]string) error {
+	return func(flags map[string]string) error {
+		opts, err := getBuildOptions(c, "generate", flags)
 		if err != nil {
-			return fmt.Errorf("please provide a datasource in the configuration file: %v", err)
+			return err
 		}
+		ctx, stop := interruptContext(log)
+		defer stop()
+		return generate(ctx, siteInfo, opts, log)
+	}
+}
 
-		_, err = ds.Fetch(siteInfo.DataSource.Repository, siteInfo.TempFolder)
+func getUploadHandler(c *cli.CLI, siteInfo config.SiteInformation, log logger.Logger) func(flags map[string]string) error {
+	return func(flags map[string]string) error {
+		dryRun, err := c.BoolValue("d", "upload", flags)
 		if err != nil {
-			return fmt.Errorf("failure to fetch posts: %v", err)
+			return fmt.Errorf("dry run flag is not correct: %v", err)
 		}
-		return nil
+		return upload(siteInfo, dryRun, log)
 	}
 }
 
-func getThemeHandler(siteInfo config.SiteInformation) func(flags map[string]string) error {
+func getRollbackHandler(siteInfo config.SiteInformation, log logger.Logger) func(flags map[string]string) error {
 	return func(flags map[string]string) error {
-		ds, err := datasource.New(siteInfo.Theme.Type)
+		build, err := generator.Rollback(siteInfo.DestFolder)
 		if err != nil {
-			return fmt.Errorf("please provide a datasource in the configuration file: %v", err)
+			return fmt.Errorf("failed to roll back: %v", err)
 		}
+		logger.Infof(log, "Restored build %s in %s", build, siteInfo.DestFolder)
+		return nil
+	}
+}
+
+func getNewPostHandler(c *cli.CLI, siteInfo config.SiteInformation, log logger.Logger) func(flags map[string]string) error {
+	return func(flags map[string]string) error {
+		title := c.StringValue("t", "new", flags)
+		short := c.StringValue("s", "new", flags)
+		tags := splitList(c.StringValue("g", "new", flags))
+		categories := splitList(c.StringValue("c", "new", flags))
+		source := c.StringValue("o", "new", flags)
 
-		_, err = ds.Fetch(siteInfo.Theme.Repository, siteInfo.ThemeFolder)
+		path, err := createPost(siteInfo, source, title, short, tags, categories)
 		if err != nil {
-			return fmt.Errorf("failure to fetch theme: %v", err)
+			return fmt.Errorf("failed to create post: %v", err)
 		}
+		logger.Infof(log, "Created post %s", path)
 		return nil
 	}
 }
 
-func getGenerateHandler(siteInfo config.SiteInformation) func(flags map[string]string) error {
+func getExampleConfigHandler(c *cli.CLI, siteInfo config.SiteInformation) func(flags map[string]string) error {
 	return func(flags map[string]string) error {
-		dirs, err := fs.GetContentFolders(siteInfo.TempFolder)
+		effective, err := c.BoolValue("e", "example", flags)
 		if err != nil {
-			return fmt.Errorf("failed to get contents from %s: %v", siteInfo.TempFolder, err)
+			return fmt.Errorf("effective flag is not correct: %v", err)
 		}
-		g := generator.NewSiteGenerator(dirs, &siteInfo)
-
-		err = g.Generate()
-		if err !=  nil {
-			return fmt.Errorf("failed to generate blog: %v", err)
+		if !effective {
+			fmt.Fprint(os.Stdout, jsonExampleLongHelp)
+			return nil
+		}
+		b, err := json.MarshalIndent(siteInfo.Masked(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to print configuration: %v", err)
 		}
+		fmt.Fprintf(os.Stdout, "%s\n", b)
 		return nil
 	}
 }
 
-func getExampleConfigHandler(siteInfo config.SiteInformation) func(flags map[string]string) error {
+func getWatchHandler(c *cli.CLI, cfg configSource, siteInfo config.SiteInformation, log logger.Logger) func(flags map[string]string) error {
 	return func(flags map[string]string) error {
-		fmt.Fprint(os.Stdout, jsonExampleLongHelp)
-		return nil
+		opts, err := getBuildOptions(c, "watch", flags)
+		if err != nil {
+			return err
+		}
+		ctx, stop := interruptContext(log)
+		defer stop()
+		return watch(ctx, cfg, siteInfo, opts, nil, log)
 	}
 }
 
-func getServerHandler(c *cli.CLI, siteInfo config.SiteInformation) func(flags map[string]string) error {
+func getServerHandler(c *cli.CLI, cfg configSource, siteInfo config.SiteInformation, log logger.Logger) func(flags map[string]string) error {
 	return func(flags map[string]string) error {
-		fs := http.FileServer(http.Dir(siteInfo.DestFolder))
-		http.Handle("/", fs)
-
 		serverPort, err := c.IntValue("p", "server", flags)
 		if err != nil {
 			return fmt.Errorf("server port is not correct: %v", err)
 		}
-
-		fmt.Fprintf(os.Stdout, "Listening @ localhost: %d/\n", serverPort)
-		http.ListenAndServe(fmt.Sprintf(":%d", serverPort), nil)
-		return nil
+		withWatch, err := c.BoolValue("w", "server", flags)
+		if err != nil {
+			return fmt.Errorf("watch flag is not correct: %v", err)
+		}
+		withLiveReload, err := c.BoolValue("l", "server", flags)
+		if err != nil {
+			return fmt.Errorf("live reload flag is not correct: %v", err)
+		}
+		opts, err := getBuildOptions(c, "server", flags)
+		if err != nil {
+			return err
+		}
+		var broker *reloadBroker
+		if withLiveReload {
+			broker = newReloadBroker()
+			withWatch = true
+		}
+		if withWatch {
+			go watchInBackground(cfg, siteInfo, opts, broker, log)
+		} else if opts.preview() {
+			// the blog is generated once more to include the hidden posts
+			ctx, stop := interruptContext(log)
+			err = generate(ctx, siteInfo, opts, log)
+			stop()
+			if err != nil {
+				return err
+			}
+		}
+		return serve(siteInfo, serverPort, broker, log)
 	}
 }
 
-func getExecAllHandler(c *cli.CLI, siteInfo config.SiteInformation) func(flags map[string]string) error {
+func getExecAllHandler(c *cli.CLI, cfg configSource, siteInfo config.SiteInformation, log logger.Logger) func(flags map[string]string) error {
 	return func(flags map[string]string) error {
-		// download posts
-		ds, err := datasource.New(siteInfo.DataSource.Type)
+		serverPort, err := c.IntValue("p", "all", flags)
 		if err != nil {
-			return fmt.Errorf("please provide a datasource in the configuration file: %v", err)
+			return fmt.Errorf("server port is not correct: %v", err)
 		}
-
-		_, err = ds.Fetch(siteInfo.DataSource.Repository, siteInfo.TempFolder)
+		withUpload, err := c.BoolValue("u", "all", flags)
 		if err != nil {
-			return fmt.Errorf("failure to fetch posts: %v", err)
+			return fmt.Errorf("upload flag is not correct: %v", err)
 		}
-
-		// download theme
-		ds, err = datasource.New(siteInfo.Theme.Type)
+		withWatch, err := c.BoolValue("w", "all", flags)
 		if err != nil {
-			return fmt.Errorf("please provide a datasource in the configuration file: %v", err)
+			return fmt.Errorf("watch flag is not correct: %v", err)
 		}
-
-		_, err = ds.Fetch(siteInfo.Theme.Repository, siteInfo.ThemeFolder)
+		withLiveReload, err := c.BoolValue("l", "all", flags)
 		if err != nil {
-			return fmt.Errorf("failure to fetch posts: %v", err)
+			return fmt.Errorf("live reload flag is not correct: %v", err)
+		}
+		opts, err := getBuildOptions(c, "all", flags)
+		if err != nil {
+			return err
 		}
 
-		// generate blog
-		dirs, err := fs.GetContentFolders(siteInfo.TempFolder)
+		// only the build stops on Ctrl-C, the web server is stopped as usual
+		ctx, stop := interruptContext(log)
+		err = fetchPosts(siteInfo, log)
+		if err == nil {
+			err = fetchTheme(siteInfo, log)
+		}
+		if err == nil {
+			err = generate(ctx, siteInfo, opts, log)
+		}
+		stop()
 		if err != nil {
-			return fmt.Errorf("failed to get contents from %s: %v", siteInfo.TempFolder, err)
+			return err
+		}
+		if withUpload {
+			err = upload(siteInfo, false, log)
+			if err != nil {
+				return err
+			}
+		}
+
+		var broker *reloadBroker
+		if withLiveReload {
+			broker = newReloadBroker()
+			withWatch = true
+		}
+		if withWatch {
+			go watchInBackground(cfg, siteInfo, opts, broker, log)
 		}
-		g := generator.NewSiteGenerator(dirs, &siteInfo)
-		err = g.Generate()
+		return serve(siteInfo, serverPort, broker, log)
+	}
+}
+
+// buildOptions are the command line options that change the generated blog
+type buildOptions struct {
+	drafts  bool
+	future  bool
+	expired bool
+	// jobs is the number of pages generated at the same time
+	jobs int
+	// force generates every page, ignoring the build cache
+	force bool
+}
+
+// preview reports whether posts that are not published should be generated
+func (o buildOptions) preview() bool {
+	return o.drafts || o.future || o.expired
+}
+
+func getBuildOptions(c *cli.CLI, command string, flags map[string]string) (opts buildOptions, err error) {
+	opts.drafts, err = c.BoolValue("drafts", command, flags)
+	if err != nil {
+		return opts, fmt.Errorf("drafts flag is not correct: %v", err)
+	}
+	opts.future, err = c.BoolValue("future", command, flags)
+	if err != nil {
+		return opts, fmt.Errorf("future flag is not correct: %v", err)
+	}
+	opts.expired, err = c.BoolValue("expired", command, flags)
+	if err != nil {
+		return opts, fmt.Errorf("expired flag is not correct: %v", err)
+	}
+	opts.jobs, err = c.IntValue("j", command, flags)
+	if err != nil {
+		return opts, fmt.Errorf("jobs flag is not correct: %v", err)
+	}
+	if opts.jobs < 0 {
+		return opts, fmt.Errorf("jobs flag must not be negative")
+	}
+	opts.force, err = c.BoolValue("force", command, flags)
+	if err != nil {
+		return opts, fmt.Errorf("force flag is not correct: %v", err)
+	}
+	return opts, nil
+}
+
+func fetchPosts(siteInfo config.SiteInformation, log logger.Logger) error {
+	for i := range siteInfo.Sources() {
+		err := fetchSource(siteInfo, i, log)
 		if err != nil {
-			return fmt.Errorf("failed to generate blog: %v", err)
+			return err
 		}
+	}
+	return nil
+}
 
-		// run web server
-		blog := http.FileServer(http.Dir(siteInfo.DestFolder))
-		http.Handle("/", blog)
+// fetchSource fetches the posts of the i-th datasource of the blog.
+func fetchSource(siteInfo config.SiteInformation, i int, log logger.Logger) error {
+	source := siteInfo.Sources()[i]
+	ds, err := datasource.New(source.Type, source.RawOptions())
+	if err != nil {
+		return fmt.Errorf("failed to create datasource: %v", err)
+	}
 
-		serverPort, err := c.IntValue("p", "all", flags)
+	datasource.SetLogger(ds, log)
+	_, err = ds.Fetch(source.Repository, siteInfo.SourceFolder(i))
+	if err != nil {
+		return fmt.Errorf("failure to fetch posts: %v", err)
+	}
+	return nil
+}
+
+func fetchTheme(siteInfo config.SiteInformation, log logger.Logger) error {
+	ds, err := datasource.New(siteInfo.Theme.Type, siteInfo.Theme.RawOptions())
+	if err != nil {
+		return fmt.Errorf("failed to create datasource: %v", err)
+	}
+
+	datasource.SetLogger(ds, log)
+	_, err = ds.Fetch(siteInfo.Theme.Repository, siteInfo.ThemeFolder)
+	if err != nil {
+		return fmt.Errorf("failure to fetch theme: %v", err)
+	}
+	return nil
+}
+
+// generate builds the blog until ctx is canceled
+func generate(ctx context.Context, siteInfo config.SiteInformation, opts buildOptions, log logger.Logger) error {
+	sources := make([]generator.Source, 0)
+	for i, source := range siteInfo.Sources() {
+		folder := siteInfo.SourceFolder(i)
+		paths, err := fs.GetContent(folder)
 		if err != nil {
-			return fmt.Errorf("server port is not correct: %v", err)
+			return fmt.Errorf("failed to get contents from %s: %v", folder, err)
 		}
+		sources = append(sources, generator.Source{
+			Paths:      paths,
+			Prefix:     source.Prefix,
+			Tags:       source.Tags,
+			Categories: source.Categories,
+		})
+	}
+	g, err := generator.New(
+		generator.WithConfig(siteInfo),
+		generator.WithSources(sources...),
+		generator.WithLogger(log),
+		generator.WithDrafts(opts.drafts),
+		generator.WithFuture(opts.future),
+		generator.WithExpired(opts.expired),
+		generator.WithConcurrency(opts.jobs),
+		generator.WithForce(opts.force),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create generator: %v", err)
+	}
+
+	_, err = g.Build(ctx)
+	if err == context.Canceled {
+		return fmt.Errorf("generation of blog was interrupted")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate blog: %v", err)
+	}
+	return nil
+}
+
+// upload pushes the generated blog to the endpoint of the configuration file.
+// When dryRun is true it only lists the files that would be sent.
+func upload(siteInfo config.SiteInformation, dryRun bool, log logger.Logger) error {
+	e, err := endpoint.New(siteInfo.Upload.Type, siteInfo.Upload.RawOptions())
+	if err != nil {
+		return fmt.Errorf("failed to create upload endpoint: %v", err)
+	}
 
-		fmt.Fprintf(os.Stdout, "Listening @ localhost: %d/\n", serverPort)
-		http.ListenAndServe(fmt.Sprintf(":%d", serverPort), nil)
+	files, err := fs.GetFiles(siteInfo.DestFolder)
+	if err != nil {
+		return fmt.Errorf("failed to get generated files from %s: %v", siteInfo.DestFolder, err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("there are no generated files in %s, run generate first", siteInfo.DestFolder)
+	}
+
+	if dryRun {
+		fmt.Fprintf(os.Stdout, "Dry run: %d files would be uploaded to %s (%s)\n",
+			len(files), siteInfo.Upload.URL, siteInfo.Upload.Type)
+		for _, file := range files {
+			fmt.Fprintf(os.Stdout, "\t%s\n", filepath.ToSlash(file))
+		}
 		return nil
 	}
+
+	// the commit of the first datasource identifies the build
+	first := siteInfo.Sources()[0]
+	source := siteInfo.SourceFolder(0)
+	if first.Type == "local" {
+		source = filepath.Join(first.Repository, first.Subdirectory)
+	} else if first.Type == "git" {
+		source = datasource.CheckoutFolder(source, first.Subdirectory)
+	}
+	target := endpoint.Target{
+		URL:      siteInfo.Upload.URL,
+		Username: siteInfo.Upload.Username,
+		Password: siteInfo.Upload.Password,
+		Branch:   siteInfo.Upload.Branch,
+		Message:  siteInfo.Upload.CommitMessage,
+		Source:   source,
+	}
+	endpoint.SetLogger(e, log)
+	err = e.Upload(siteInfo.DestFolder, target)
+	if err != nil {
+		return fmt.Errorf("failed to upload blog: %v", err)
+	}
+	return nil
+}
+
+// watchInBackground runs watch and reports when it stops, so that
+// the web server keeps running. When broker is not nil the open
+// pages are reloaded after every rebuild.
+func watchInBackground(cfg configSource, siteInfo config.SiteInformation, opts buildOptions, broker *reloadBroker, log logger.Logger) {
+	var rebuilt func()
+	if broker != nil {
+		rebuilt = broker.reload
+	}
+	err := watch(context.Background(), cfg, siteInfo, opts, rebuilt, log)
+	if err != nil {
+		logger.Errorf(log, "stopped watching for changes: %v", err)
+	}
+}
+
+// serve runs a web server for the generated blog. When broker is not nil
+// the live reload script is added to the served pages. A blog hosted under
+// a path is served under the same path.
+func serve(siteInfo config.SiteInformation, serverPort int, broker *reloadBroker, log logger.Logger) error {
+	urls, err := url.NewBuilder(siteInfo.BlogURL)
+	if err != nil {
+		return err
+	}
+	blog := http.FileServer(http.Dir(siteInfo.DestFolder))
+	if broker != nil {
+		http.Handle(liveReloadPath, broker)
+		blog = injectLiveReload(blog)
+	}
+	base := urls.BasePath()
+	http.Handle(base, http.StripPrefix(strings.TrimSuffix(base, "/"), blog))
+	if base != "/" {
+		http.Handle("/", http.RedirectHandler(base, http.StatusFound))
+	}
+
+	logger.Infof(log, "Listening @ localhost: %d%s", serverPort, base)
+	return http.ListenAndServe(fmt.Sprintf(":%d", serverPort), nil)
 }
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	liveReloadPath   = "/_livereload"
+	liveReloadScript = `<script>(function(){` +
+		`var s=new EventSource("` + liveReloadPath + `");` +
+		`s.addEventListener("reload",function(){location.reload();});` +
+		`})();</script>`
+	// keepAliveInterval is how often a comment is sent to idle connections
+	keepAliveInterval = 30 * time.Second
+)
+
+// reloadBroker keeps the open live reload connections and
+// tells them to reload the page with Server-Sent Events.
+type reloadBroker struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]struct{}
+}
+
+func newReloadBroker() *reloadBroker {
+	return &reloadBroker{clients: make(map[chan struct{}]struct{})}
+}
+
+// reload sends a reload event to every open connection
+func (b *reloadBroker) reload() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for client := range b.clients {
+		select {
+		case client <- struct{}{}:
+		default:
+			// a reload is already pending for this client
+		}
+	}
+}
+
+// ServeHTTP keeps the connection open and streams the reload events
+func (b *reloadBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	client := make(chan struct{}, 1)
+	b.mu.Lock()
+	b.clients[client] = struct{}{}
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.clients, client)
+		b.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-client:
+			fmt.Fprint(w, "event: reload\ndata: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// injectLiveReload adds the live reload script to the HTML responses of next.
+// The files on disk are not changed. HEAD requests have no body to add the
+// script to, so their responses are sent as they are.
+func injectLiveReload(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		iw := &injectingWriter{ResponseWriter: w}
+		next.ServeHTTP(iw, r)
+		iw.finish()
+	})
+}
+
+// injectingWriter holds back HTML responses so that the
+// live reload script can be added before they are sent.
+type injectingWriter struct {
+	http.ResponseWriter
+	buf         bytes.Buffer
+	status      int
+	html        bool
+	wroteHeader bool
+}
+
+func (w *injectingWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	w.html = status == http.StatusOK &&
+		strings.HasPrefix(w.Header().Get("Content-Type"), "text/html")
+	if !w.html {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *injectingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.html {
+		return w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// finish sends the held back HTML response with the live reload script
+func (w *injectingWriter) finish() {
+	if !w.html {
+		return
+	}
+	body := injectScript(w.buf.Bytes())
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
+
+// injectScript adds the live reload script before the closing body tag,
+// or at the end of the page when there is none.
+func injectScript(page []byte) []byte {
+	script := []byte(liveReloadScript)
+	i := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+	if i == -1 {
+		return append(page, script...)
+	}
+	result := make([]byte, 0, len(page)+len(script))
+	result = append(result, page[:i]...)
+	result = append(result, script...)
+	return append(result, page[i:]...)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestInjectScript(t *testing.T) {
+	tests := []struct {
+		page, expected string
+	}{
+		{"<html><body><p>post</p></body></html>", "<html><body><p>post</p>" + liveReloadScript + "</body></html>"},
+		{"<html><BODY>a</body><p>b</p></BODY></html>", "<html><BODY>a</body><p>b</p>" + liveReloadScript + "</BODY></html>"},
+		{"<p>post</p>", "<p>post</p>" + liveReloadScript},
+		{"", liveReloadScript},
+	}
+
+	for _, tt := range tests {
+		result := string(injectScript([]byte(tt.page)))
+		if result != tt.expected {
+			t.Errorf("expected '%s', got '%s'", tt.expected, result)
+		}
+	}
+}
+
+func TestInjectLiveReload(t *testing.T) {
+	tests := []struct {
+		method, contentType, page string
+		inject                    bool
+	}{
+		{http.MethodGet, "text/html; charset=utf-8", "<body>post</body>", true},
+		{http.MethodGet, "text/html; charset=utf-8", "<p>post</p>", true},
+		{http.MethodHead, "text/html; charset=utf-8", "<body>post</body>", false},
+		{http.MethodGet, "text/css; charset=utf-8", "body{}", false},
+		{http.MethodGet, "application/xml", "<rss></rss>", false},
+	}
+
+	for _, tt := range tests {
+		handler := injectLiveReload(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tt.contentType)
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader([]byte(tt.page)))
+		}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/", nil))
+
+		body := tt.page
+		if tt.inject {
+			body = string(injectScript([]byte(tt.page)))
+		}
+		length := strconv.Itoa(len(body))
+		if tt.method == http.MethodHead {
+			body = ""
+		}
+		if rec.Code != http.StatusOK || rec.Body.String() != body {
+			t.Errorf("%s %s: expected '%s', got %d '%s'", tt.method, tt.contentType, body, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("Content-Length") != length {
+			t.Errorf("%s %s: expected length %s, got %s", tt.method, tt.contentType, length, rec.Header().Get("Content-Length"))
+		}
+	}
+}
@@ -3,34 +3,216 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/RomanosTrechlis/blog-gen/config"
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
 	"github.com/RomanosTrechlis/go-icls/cli"
 )
 
-func createCommandTree(siteInfo config.SiteInformation) *cli.CLI {
+// defaultConfigFile is used when no configuration file is given or found
+const defaultConfigFile = "config.json"
+
+// configSource is the configuration file of the blog and its profile
+type configSource struct {
+	file string
+	env  string
+}
+
+// load reads and validates the configuration
+func (s configSource) load() (config.SiteInformation, error) {
+	siteInfo, err := config.NewProfile(s.file, s.env)
+	if err != nil {
+		return siteInfo, err
+	}
+	return siteInfo, siteInfo.Validate()
+}
+
+// files returns the absolute paths of the files the configuration is read from
+func (s configSource) files() ([]string, error) {
+	file, err := filepath.Abs(s.file)
+	if err != nil {
+		return nil, err
+	}
+	if s.env == "" {
+		return []string{file}, nil
+	}
+	return []string{file, config.ProfileFile(file, s.env)}, nil
+}
+
+// logOptions are the global flags of the messages of the program
+type logOptions struct {
+	quiet   bool
+	verbose bool
+	// format is text or json
+	format string
+}
+
+// logger creates the logger of the program. Quiet shows only warnings and
+// errors, verbose shows the progress of every page too.
+func (o logOptions) logger() (logger.Logger, error) {
+	if o.quiet && o.verbose {
+		return nil, fmt.Errorf("--quiet and --verbose cannot be used together")
+	}
+	level := logger.Info
+	if o.quiet {
+		level = logger.Warn
+	} else if o.verbose {
+		level = logger.Debug
+	}
+	return logger.New(os.Stdout, os.Stderr, level, o.format)
+}
+
+func createCommandTree(cfg configSource, siteInfo config.SiteInformation, log logger.Logger) *cli.CLI {
 	c := cli.New()
-	c.New("posts", getPostsShortHelp, getPostsLongHelp, getPostHandler(siteInfo))
-	c.New("theme", getThemeShortHelp, getThemeLongHelp, getThemeHandler(siteInfo))
-	c.New("generate", generateShortHelp, generateLongHelp, getGenerateHandler(siteInfo))
-	c.New("example", jsonExampleShortHelp, jsonExampleLongHelp, getExampleConfigHandler(siteInfo))
-	server := c.New("server", runShortHelp, runLongHelp, getServerHandler(c, siteInfo))
+	c.New("posts", getPostsShortHelp, getPostsLongHelp, getPostHandler(siteInfo, log))
+	c.New("theme", getThemeShortHelp, getThemeLongHelp, getThemeHandler(siteInfo, log))
+	gen := c.New("generate", generateShortHelp, generateLongHelp, getGenerateHandler(c, siteInfo, log))
+	addBuildFlags(gen)
+	n := c.New("new", newPostShortHelp, newPostLongHelp, getNewPostHandler(c, siteInfo, log))
+	n.StringFlag("t", "title", "", "title of the post", true)
+	n.StringFlag("s", "short", "", "short description of the post", false)
+	n.StringFlag("g", "tags", "", "comma separated tags of the post", false)
+	n.StringFlag("c", "categories", "", "comma separated categories of the post", false)
+	n.StringFlag("o", "source", "", "name of the datasource to create the post in", false)
+	up := c.New("upload", uploadShortHelp, uploadLongHelp, getUploadHandler(c, siteInfo, log))
+	up.BoolFlag("d", "dry-run", "lists the files that would be uploaded without pushing them", false)
+	ex := c.New("example", jsonExampleShortHelp, jsonExampleLongHelp, getExampleConfigHandler(c, siteInfo))
+	ex.BoolFlag("e", "effective", "prints the configuration in use, with its secrets masked", false)
+	c.New("rollback", rollbackShortHelp, rollbackLongHelp, getRollbackHandler(siteInfo, log))
+	w := c.New("watch", watchShortHelp, watchLongHelp, getWatchHandler(c, cfg, siteInfo, log))
+	addBuildFlags(w)
+	server := c.New("server", runShortHelp, runLongHelp, getServerHandler(c, cfg, siteInfo, log))
 	server.IntFlag("p", "port", 8080, "port for web server", false)
-	all := c.New("all", execAllShortHelp, execAllLongHelp, getExecAllHandler(c, siteInfo))
+	server.BoolFlag("w", "watch", "rebuilds the blog when posts, theme or configuration change", false)
+	server.BoolFlag("l", "livereload", "reloads the open pages after every rebuild, implies --watch", false)
+	addBuildFlags(server)
+	all := c.New("all", execAllShortHelp, execAllLongHelp, getExecAllHandler(c, cfg, siteInfo, log))
 	all.IntFlag("p", "port", 8080, "port for web server", false)
+	all.BoolFlag("w", "watch", "rebuilds the blog when posts, theme or configuration change", false)
+	all.BoolFlag("l", "livereload", "reloads the open pages after every rebuild, implies --watch", false)
+	addBuildFlags(all)
+	all.BoolFlag("u", "upload", "uploads the generated blog before running the web server", false)
 	return c
 }
 
+// addBuildFlags adds the flags that change the generated blog to cmd
+func addBuildFlags(cmd interface {
+	BoolFlag(name, alias string, description string, isRequired bool)
+	IntFlag(name, alias string, value int, description string, isRequired bool)
+}) {
+	cmd.BoolFlag("drafts", "", "includes the posts marked as drafts", false)
+	cmd.BoolFlag("future", "", "includes the posts with a publish date in the future", false)
+	cmd.BoolFlag("expired", "", "includes the posts with an expiry date in the past", false)
+	cmd.IntFlag("j", "jobs", 0, "number of pages generated at the same time, 0 for one per CPU", false)
+	cmd.BoolFlag("force", "", "generates every page, instead of reusing the unchanged pages of the previous build", false)
+}
+
+// globalFlags removes the global --config, --env, --quiet, --verbose and
+// --log-format flags from args and returns the configuration and the
+// messages they select. Without --config the configuration file is
+// searched from the working directory upwards, falling back to
+// config.json.
+func globalFlags(args []string) (configSource, logOptions, []string, error) {
+	var cfg configSource
+	var logs logOptions
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name := strings.TrimLeft(arg, "-")
+		value := ""
+		if j := strings.Index(name, "="); j >= 0 && strings.HasPrefix(arg, "--") {
+			name, value = name[:j], name[j+1:]
+		}
+		if !strings.HasPrefix(arg, "-") {
+			rest = append(rest, arg)
+			continue
+		}
+		switch name {
+		case "q", "quiet":
+			logs.quiet = true
+			continue
+		case "v", "verbose":
+			logs.verbose = true
+			continue
+		case "config", "env", "log-format":
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+		if value == "" {
+			if i+1 == len(args) {
+				return cfg, logs, args, fmt.Errorf("flag %s needs a value", arg)
+			}
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "config":
+			cfg.file = value
+		case "env":
+			cfg.env = value
+		default:
+			logs.format = value
+		}
+	}
+	if cfg.file != "" {
+		return cfg, logs, rest, nil
+	}
+	path, err := config.Find(".")
+	if err != nil || path == "" {
+		path = defaultConfigFile
+	}
+	cfg.file = path
+	return cfg, logs, rest, err
+}
+
+// needsNoConfig reports whether args ask for the help or the example
+// configuration, which work without a valid configuration
+func needsNoConfig(args []string) bool {
+	if len(args) > 0 && args[0] == "example" {
+		return true
+	}
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			return true
+		}
+	}
+	return false
+}
+
 func main() {
-	args := os.Args[1:]
+	cfg, logs, args, err := globalFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	log, err := logs.logger()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	// relative paths of the configuration are relative to its folder
+	cfg.file, err = filepath.Abs(cfg.file)
+	if err == nil {
+		err = os.Chdir(filepath.Dir(cfg.file))
+	}
+	if err != nil {
+		logger.Errorf(log, "failed to use configuration folder: %v", err)
+		os.Exit(1)
+	}
+
 	line := ""
-	siteInfo, err := config.New("config.json")
+	siteInfo, err := cfg.load()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "config.json reading error: %v\n", err)
-		args = append(args, "-h")
+		logger.Errorf(log, "%s reading error: %v", cfg.file, err)
+		if !needsNoConfig(args) {
+			os.Exit(1)
+		}
 	}
 
-	c := createCommandTree(siteInfo)
+	c := createCommandTree(cfg, siteInfo, log)
 
 	if len(args) == 0 {
 		args = append(args, "-h")
@@ -42,7 +224,7 @@ func main() {
 
 	_, err = c.Execute(line)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "command exited with error: %v\n", err)
+		logger.Errorf(log, "command failed: %v", err)
 		os.Exit(1)
 	}
 }
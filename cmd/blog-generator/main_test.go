@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestNeedsNoConfig(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected bool
+	}{
+		{nil, false},
+		{[]string{"generate"}, false},
+		{[]string{"server", "-p", "80"}, false},
+		{[]string{"-h"}, true},
+		{[]string{"generate", "--help"}, true},
+		{[]string{"example"}, true},
+	}
+
+	for _, tt := range tests {
+		if needsNoConfig(tt.args) != tt.expected {
+			t.Errorf("%v: expected %v, got %v", tt.args, tt.expected, !tt.expected)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/RomanosTrechlis/blog-gen/config"
+	"github.com/RomanosTrechlis/blog-gen/util/url"
+	"gopkg.in/yaml.v2"
+)
+
+// postMeta is the content of the meta.yml of a new post
+type postMeta struct {
+	Title      string   `yaml:"title"`
+	Short      string   `yaml:"short"`
+	Date       string   `yaml:"date"`
+	Tags       []string `yaml:"tags"`
+	Categories []string `yaml:"categories"`
+}
+
+// createPost creates the folder of a new post inside the local
+// datasource with a meta.yml and an empty post.md. When the blog has
+// many datasources, name selects one of them.
+// It returns the path of the created folder.
+func createPost(siteInfo config.SiteInformation, name, title, short string, tags, categories []string) (string, error) {
+	source, err := localSource(siteInfo, name)
+	if err != nil {
+		return "", err
+	}
+	slug := url.Slugify(title)
+	if slug == "" {
+		return "", fmt.Errorf("couldn't create a folder name from title '%s'", title)
+	}
+
+	path := filepath.Join(source.Repository, source.Subdirectory, slug)
+	_, err = os.Stat(path)
+	if err == nil {
+		return "", fmt.Errorf("post folder %s already exists", path)
+	}
+	if !os.IsNotExist(err) {
+		return "", fmt.Errorf("error accessing directory %s: %v", path, err)
+	}
+
+	meta := postMeta{
+		Title:      title,
+		Short:      short,
+		Date:       time.Now().Format(siteInfo.DateFormat),
+		Tags:       tags,
+		Categories: categories,
+	}
+	b, err := yaml.Marshal(meta)
+	if err != nil {
+		return "", fmt.Errorf("error creating meta.yml for %s: %v", title, err)
+	}
+
+	err = os.MkdirAll(path, os.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("error creating directory %s: %v", path, err)
+	}
+	metaPath := filepath.Join(path, "meta.yml")
+	err = ioutil.WriteFile(metaPath, b, 0644)
+	if err != nil {
+		return "", fmt.Errorf("error writing file %s: %v", metaPath, err)
+	}
+	postPath := filepath.Join(path, "post.md")
+	err = ioutil.WriteFile(postPath, nil, 0644)
+	if err != nil {
+		return "", fmt.Errorf("error writing file %s: %v", postPath, err)
+	}
+	return path, nil
+}
+
+// localSource returns the datasource named name, or the first local
+// datasource of the blog when name is empty.
+func localSource(siteInfo config.SiteInformation, name string) (config.DataSource, error) {
+	for _, source := range siteInfo.Sources() {
+		if name == "" && source.Type == "local" {
+			return source, nil
+		}
+		if name == "" || source.Name != name {
+			continue
+		}
+		if source.Type != "local" {
+			return source, fmt.Errorf("new posts can only be created in a local datasource, found '%s'", source.Type)
+		}
+		return source, nil
+	}
+	if name != "" {
+		return config.DataSource{}, fmt.Errorf("there is no datasource named '%s'", name)
+	}
+	return config.DataSource{}, fmt.Errorf("new posts can only be created in a local datasource")
+}
+
+// splitList splits a comma separated flag value
+func splitList(value string) []string {
+	result := []string{}
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
+)
+
+// interruptContext returns a context that is canceled on the first
+// Ctrl-C, so that the build stops cleanly. A second Ctrl-C stops the
+// program at once. The returned function stops catching the signals.
+func interruptContext(log logger.Logger) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sig:
+			logger.Warnf(log, "Interrupted, stopping...")
+			signal.Stop(sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sig)
+		cancel()
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/RomanosTrechlis/blog-gen/config"
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
+	"github.com/fsnotify/fsnotify"
+)
+
+// debounceDelay is the time to wait after the last change before rebuilding,
+// so that rapid saves result in a single rebuild.
+const debounceDelay = 300 * time.Millisecond
+
+// watch builds the blog and then rebuilds it every time the posts, the theme
+// or the configuration file change. Build failures are reported and the
+// watching continues. The rebuilt function, if not nil, is called after
+// every successful build. Watching stops when ctx is canceled.
+func watch(ctx context.Context, cfg configSource, siteInfo config.SiteInformation, opts buildOptions, rebuilt func(), log logger.Logger) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %v", err)
+	}
+	defer w.Close()
+
+	configFiles, err := cfg.files()
+	if err != nil {
+		return fmt.Errorf("failed to resolve path of %s: %v", cfg.file, err)
+	}
+	// editors usually replace files on save, so the folder of the
+	// configuration file is watched instead of the file itself
+	err = w.Add(filepath.Dir(configFiles[0]))
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %v", cfg.file, err)
+	}
+	roots, err := addWatchedFolders(w, nil, siteInfo)
+	if err != nil {
+		return err
+	}
+
+	runRebuild(ctx, siteInfo, opts, rebuilt, log)
+	logger.Infof(log, "Watching for changes in %s...", strings.Join(roots, ", "))
+
+	var timer <-chan time.Time
+	configChanged := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if isInside(event.Name, configFiles) {
+				configChanged = true
+				timer = time.After(debounceDelay)
+				continue
+			}
+			if !isInside(event.Name, roots) || isTemporary(event.Name) {
+				continue
+			}
+			if event.Op&fsnotify.Create == fsnotify.Create {
+				err := addFolder(w, event.Name)
+				if err != nil {
+					logger.Warnf(log, "failed to watch %s: %v", event.Name, err)
+				}
+			}
+			timer = time.After(debounceDelay)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Warnf(log, "watch error: %v", err)
+		case <-timer:
+			timer = nil
+			if configChanged {
+				configChanged = false
+				si, err := cfg.load()
+				if err != nil {
+					logger.Warnf(log, "failed to reload %s, keeping previous configuration: %v", cfg.file, err)
+				} else {
+					siteInfo = si
+					roots, err = addWatchedFolders(w, roots, siteInfo)
+					if err != nil {
+						logger.Warnf(log, "%v", err)
+					}
+				}
+			}
+			runRebuild(ctx, siteInfo, opts, rebuilt, log)
+		}
+	}
+}
+
+// runRebuild rebuilds the blog and reports the outcome without stopping
+func runRebuild(ctx context.Context, siteInfo config.SiteInformation, opts buildOptions, rebuilt func(), log logger.Logger) {
+	start := time.Now()
+	err := rebuild(ctx, siteInfo, opts, log)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		logger.Errorf(log, "rebuild failed: %v", err)
+		return
+	}
+	logger.Infof(log, "Rebuild finished in %v.", time.Since(start).Round(time.Millisecond))
+	if rebuilt != nil {
+		rebuilt()
+	}
+}
+
+// rebuild copies the local posts and theme and generates the blog.
+// Posts and themes from git are not fetched again.
+func rebuild(ctx context.Context, siteInfo config.SiteInformation, opts buildOptions, log logger.Logger) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	for i, source := range siteInfo.Sources() {
+		if source.Type != "local" {
+			continue
+		}
+		err = fetchSource(siteInfo, i, log)
+		if err != nil {
+			return err
+		}
+	}
+	if siteInfo.Theme.Type == "local" {
+		err = fetchTheme(siteInfo, log)
+		if err != nil {
+			return err
+		}
+	}
+	return generate(ctx, siteInfo, opts, log)
+}
+
+// watchedFolders returns the folders that contain the sources of the blog.
+// Local datasources are watched at their repository, since the temporary
+// folders are overwritten on every rebuild.
+func watchedFolders(siteInfo config.SiteInformation) []string {
+	folders := make([]string, 0)
+	for i, source := range siteInfo.Sources() {
+		posts := siteInfo.SourceFolder(i)
+		if source.Type == "local" {
+			posts = filepath.Join(source.Repository, source.Subdirectory)
+		}
+		folders = append(folders, posts)
+	}
+	theme := siteInfo.ThemeFolder
+	if siteInfo.Theme.Type == "local" {
+		theme = filepath.Join(siteInfo.Theme.Repository, siteInfo.Theme.Subdirectory)
+	}
+	return append(folders, theme)
+}
+
+// addWatchedFolders adds the source folders of siteInfo that are not
+// already watched and returns the updated list of watched folders.
+func addWatchedFolders(w *fsnotify.Watcher, roots []string, siteInfo config.SiteInformation) ([]string, error) {
+	for _, folder := range watchedFolders(siteInfo) {
+		abs, err := filepath.Abs(folder)
+		if err != nil {
+			return roots, fmt.Errorf("failed to resolve path of %s: %v", folder, err)
+		}
+		if isInside(abs, roots) {
+			continue
+		}
+		err = addFolder(w, abs)
+		if err != nil {
+			return roots, fmt.Errorf("failed to watch %s: %v", folder, err)
+		}
+		roots = append(roots, abs)
+	}
+	return roots, nil
+}
+
+// addFolder watches path and all of its subfolders, skipping hidden ones.
+// Paths that are not folders are ignored.
+func addFolder(w *fsnotify.Watcher, path string) error {
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if p != path && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return w.Add(p)
+	})
+}
+
+// isTemporary reports whether path is a hidden or backup file of an editor
+func isTemporary(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~")
+}
+
+func isInside(path string, roots []string) bool {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,65 +3,342 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
 )
 
+// Names are the names of the configuration files searched by Find
+var Names = []string{"blog-gen.json", "blog-gen.yml", "blog-gen.yaml", "blog-gen.toml"}
+
+// Find searches dir and its parents for one of Names and returns its
+// path. It returns an empty path if none is found.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range Names {
+			path := filepath.Join(dir, name)
+			info, err := os.Stat(path)
+			if err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
 // SiteInformation contains the information inside ConfigFile
 type SiteInformation struct {
-	Author            string `json:"Author"`
-	BlogURL           string `json:"BlogURL"`
-	BlogLanguage      string `json:"BlogLanguage"`
-	BlogDescription   string `json:"BlogDescription"`
-	DateFormat        string `json:"DateFormat"`
-	Theme             Theme
-	ThemeFolder       string `json:"ThemeFolder"`
-	BlogTitle         string `json:"BlogTitle"`
-	NumPostsFrontPage int    `json:"NumPostsFrontPage"`
-	DataSource        DataSource
-	Upload            Upload
-	TempFolder        string       `json:"TempFolder"`
-	DestFolder        string       `json:"DestFolder"`
-	StaticPages       []StaticPage `json:"StaticPages"`
+	Author            string       `json:"Author" yaml:"Author" toml:"Author"`
+	BlogURL           string       `json:"BlogURL" yaml:"BlogURL" toml:"BlogURL"`
+	BlogLanguage      string       `json:"BlogLanguage" yaml:"BlogLanguage" toml:"BlogLanguage"`
+	BlogDescription   string       `json:"BlogDescription" yaml:"BlogDescription" toml:"BlogDescription"`
+	DateFormat        string       `json:"DateFormat" yaml:"DateFormat" toml:"DateFormat"`
+	Theme             Theme        `json:"Theme" yaml:"Theme" toml:"Theme"`
+	ThemeFolder       string       `json:"ThemeFolder" yaml:"ThemeFolder" toml:"ThemeFolder"`
+	BlogTitle         string       `json:"BlogTitle" yaml:"BlogTitle" toml:"BlogTitle"`
+	NumPostsFrontPage int          `json:"NumPostsFrontPage" yaml:"NumPostsFrontPage" toml:"NumPostsFrontPage"`
+	DataSource        DataSource   `json:"DataSource" yaml:"DataSource" toml:"DataSource"`
+	DataSources       []DataSource `json:"DataSources" yaml:"DataSources" toml:"DataSources"`
+	Upload            Upload       `json:"Upload" yaml:"Upload" toml:"Upload"`
+	TempFolder        string       `json:"TempFolder" yaml:"TempFolder" toml:"TempFolder"`
+	DestFolder        string       `json:"DestFolder" yaml:"DestFolder" toml:"DestFolder"`
+	StaticPages       []StaticPage `json:"StaticPages" yaml:"StaticPages" toml:"StaticPages"`
+	Permalinks        Permalinks   `json:"Permalinks" yaml:"Permalinks" toml:"Permalinks"`
+	// UglyURLs generates posts and taxonomy pages as .html files
+	// instead of folders with an index.html
+	UglyURLs bool `json:"UglyURLs" yaml:"UglyURLs" toml:"UglyURLs"`
+	// KeepBuilds is the number of previous builds of DestFolder that are
+	// kept next to it, so that they can be restored with rollback
+	KeepBuilds int `json:"KeepBuilds" yaml:"KeepBuilds" toml:"KeepBuilds"`
+}
+
+// Permalinks holds the url patterns of the pages. A pattern can use the
+// tokens :year, :month, :day, :slug, :section and :category.
+type Permalinks struct {
+	Post     string `json:"Post" yaml:"Post" toml:"Post"`
+	Tag      string `json:"Tag" yaml:"Tag" toml:"Tag"`
+	Category string `json:"Category" yaml:"Category" toml:"Category"`
 }
 
 type Theme struct {
-	Repository string `json:"Repository"`
-	Type       string `json:"Type"`
+	Repository   string `json:"Repository" yaml:"Repository" toml:"Repository"`
+	Type         string `json:"Type" yaml:"Type" toml:"Type"`
+	Ref          string `json:"Ref" yaml:"Ref" toml:"Ref"`
+	Subdirectory string `json:"Subdirectory" yaml:"Subdirectory" toml:"Subdirectory"`
+	Auth         Auth   `json:"Auth" yaml:"Auth" toml:"Auth"`
+	// StripComponents removes leading folders from the entries of an archive
+	StripComponents int `json:"StripComponents" yaml:"StripComponents" toml:"StripComponents"`
+	// Options are type specific settings of the datasource
+	Options map[string]interface{} `json:"Options" yaml:"Options" toml:"Options"`
 }
 
 type StaticPage struct {
-	File       string `json:"File"`
-	To         string `json:"To"`
-	IsTemplate bool   `json:"IsTemplate"`
+	File       string `json:"File" yaml:"File" toml:"File"`
+	To         string `json:"To" yaml:"To" toml:"To"`
+	IsTemplate bool   `json:"IsTemplate" yaml:"IsTemplate" toml:"IsTemplate"`
 }
 
 type DataSource struct {
-	Type       string `json:"Type"`
-	Repository string `json:"Repository"`
+	// Name is the folder inside TempFolder the posts are fetched into
+	Name         string `json:"Name" yaml:"Name" toml:"Name"`
+	Type         string `json:"Type" yaml:"Type" toml:"Type"`
+	Repository   string `json:"Repository" yaml:"Repository" toml:"Repository"`
+	Ref          string `json:"Ref" yaml:"Ref" toml:"Ref"`
+	Subdirectory string `json:"Subdirectory" yaml:"Subdirectory" toml:"Subdirectory"`
+	Auth         Auth   `json:"Auth" yaml:"Auth" toml:"Auth"`
+	// StripComponents removes leading folders from the entries of an archive
+	StripComponents int `json:"StripComponents" yaml:"StripComponents" toml:"StripComponents"`
+	// Options are type specific settings of the datasource
+	Options map[string]interface{} `json:"Options" yaml:"Options" toml:"Options"`
+	// Prefix is prepended to the URL of every post of the datasource
+	Prefix     string   `json:"Prefix" yaml:"Prefix" toml:"Prefix"`
+	Tags       []string `json:"Tags" yaml:"Tags" toml:"Tags"`
+	Categories []string `json:"Categories" yaml:"Categories" toml:"Categories"`
+}
+
+// Auth holds the credentials of a private git repository
+type Auth struct {
+	SSHKey           string `json:"SSHKey" yaml:"SSHKey" toml:"SSHKey"`
+	TokenEnv         string `json:"TokenEnv" yaml:"TokenEnv" toml:"TokenEnv"`
+	Username         string `json:"Username" yaml:"Username" toml:"Username"`
+	CredentialHelper string `json:"CredentialHelper" yaml:"CredentialHelper" toml:"CredentialHelper"`
 }
 
 type Upload struct {
-	Type     string `json:"Type"`
-	URL      string `json:"URL"`
-	Username string `json:"Username"`
-	Password string `json:"Password"`
+	Type          string `json:"Type" yaml:"Type" toml:"Type"`
+	URL           string `json:"URL" yaml:"URL" toml:"URL"`
+	Username      string `json:"Username" yaml:"Username" toml:"Username"`
+	Password      string `json:"Password" yaml:"Password" toml:"Password" secret:"true"`
+	Branch        string `json:"Branch" yaml:"Branch" toml:"Branch"`
+	CommitMessage string `json:"CommitMessage" yaml:"CommitMessage" toml:"CommitMessage"`
+	// Options are type specific settings of the endpoint
+	Options map[string]interface{} `json:"Options" yaml:"Options" toml:"Options"`
+}
+
+// RawOptions returns the Options of the theme together with its
+// well-known settings, as given to the datasource factories.
+func (t Theme) RawOptions() map[string]interface{} {
+	return sourceOptions(t.Options, t.Ref, t.Subdirectory, t.Auth, t.StripComponents)
+}
+
+// RawOptions returns the Options of the datasource together with its
+// well-known settings, as given to the datasource factories.
+func (ds DataSource) RawOptions() map[string]interface{} {
+	return sourceOptions(ds.Options, ds.Ref, ds.Subdirectory, ds.Auth, ds.StripComponents)
+}
+
+// RawOptions returns the Options of the upload together with its
+// well-known settings, as given to the endpoint factories.
+func (u Upload) RawOptions() map[string]interface{} {
+	options := copyOptions(u.Options)
+	setOption(options, "URL", u.URL)
+	setOption(options, "Username", u.Username)
+	setOption(options, "Password", u.Password)
+	setOption(options, "Branch", u.Branch)
+	setOption(options, "CommitMessage", u.CommitMessage)
+	return options
+}
+
+func sourceOptions(other map[string]interface{}, ref, subdirectory string, auth Auth, strip int) map[string]interface{} {
+	options := copyOptions(other)
+	setOption(options, "Ref", ref)
+	setOption(options, "Subdirectory", subdirectory)
+	if strip != 0 {
+		options["StripComponents"] = strip
+	}
+	a := make(map[string]interface{})
+	setOption(a, "SSHKey", auth.SSHKey)
+	setOption(a, "TokenEnv", auth.TokenEnv)
+	setOption(a, "Username", auth.Username)
+	setOption(a, "CredentialHelper", auth.CredentialHelper)
+	if len(a) > 0 {
+		options["Auth"] = a
+	}
+	return options
+}
+
+func copyOptions(other map[string]interface{}) map[string]interface{} {
+	options := make(map[string]interface{}, len(other))
+	for k, v := range other {
+		options[k] = v
+	}
+	return options
+}
+
+// setOption sets a well-known setting, unless it is empty
+func setOption(options map[string]interface{}, key, value string) {
+	if value != "" {
+		options[key] = value
+	}
 }
 
+// New reads configFile and overrides its fields with the environment
+// variables that start with EnvPrefix.
 func New(configFile string) (SiteInformation, error) {
-	data, err := ioutil.ReadFile(configFile)
+	return NewProfile(configFile, "")
+}
+
+// NewProfile reads configFile, overlays the file of the profile env when
+// env is not empty and overrides the fields with the environment variables
+// that start with EnvPrefix. The profile file holds only the fields that
+// differ from configFile.
+func NewProfile(configFile, env string) (SiteInformation, error) {
+	siteInfo := new(SiteInformation)
+	err := siteInfo.readFile(configFile)
 	if err != nil {
-		return SiteInformation{}, fmt.Errorf("error accessing directory %s: %v", configFile, err)
+		return SiteInformation{}, err
+	}
+	if env != "" {
+		err = siteInfo.readFile(ProfileFile(configFile, env))
+		if err != nil {
+			return SiteInformation{}, fmt.Errorf("error reading profile %s: %v", env, err)
+		}
+	}
+	err = siteInfo.applyEnv(os.Environ())
+	if err != nil {
+		return SiteInformation{}, err
 	}
-	siteInfo := new(SiteInformation)
-	siteInfo.parseJSON(data)
 	siteInfo.fillDefaultValues()
 	return *siteInfo, nil
 }
 
+// readFile decodes configFile into si, keeping the fields it doesn't set
+func (si *SiteInformation) readFile(configFile string) error {
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("error reading file %s: %v", configFile, err)
+	}
+	switch strings.ToLower(filepath.Ext(configFile)) {
+	case ".yml", ".yaml":
+		err = si.parseYAML(data)
+	case ".toml":
+		err = si.parseTOML(data)
+	default:
+		err = si.parseJSON(data)
+	}
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %v", configFile, err)
+	}
+	return nil
+}
+
+// parseJSON decodes b into si. Keys that do not match a field are errors,
+// since they are usually typos.
 func (si *SiteInformation) parseJSON(b []byte) (err error) {
-	return json.Unmarshal(b, &si)
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	err = d.Decode(si)
+	if err != nil {
+		return jsonError(b, err)
+	}
+	if d.More() {
+		return fmt.Errorf("unexpected data after the configuration object")
+	}
+	return nil
+}
+
+// parseYAML decodes b into si, rejecting unknown keys like parseJSON
+func (si *SiteInformation) parseYAML(b []byte) (err error) {
+	err = yaml.UnmarshalStrict(b, si)
+	if err != nil {
+		return err
+	}
+	si.Theme.Options = stringMap(si.Theme.Options)
+	si.DataSource.Options = stringMap(si.DataSource.Options)
+	for i := range si.DataSources {
+		si.DataSources[i].Options = stringMap(si.DataSources[i].Options)
+	}
+	si.Upload.Options = stringMap(si.Upload.Options)
+	return nil
+}
+
+// stringMap converts the nested maps yaml decodes with interface{} keys,
+// so that options look the same whatever the format of the file.
+func stringMap(m map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		m[k] = stringKeys(v)
+	}
+	return m
+}
+
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			m[fmt.Sprintf("%v", k)] = stringKeys(value)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = stringKeys(v[i])
+		}
+	}
+	return v
+}
+
+// parseTOML decodes b into si, rejecting unknown keys like parseJSON
+func (si *SiteInformation) parseTOML(b []byte) (err error) {
+	md, err := toml.Decode(string(b), si)
+	if err != nil {
+		return err
+	}
+	for _, key := range md.Undecoded() {
+		// the content of the free form options is not decoded in fields
+		if isOptionsKey(key) {
+			continue
+		}
+		return fmt.Errorf("unknown field \"%s\"", key)
+	}
+	return nil
+}
+
+func isOptionsKey(key toml.Key) bool {
+	for i := 1; i < len(key)-1; i++ {
+		if key[i] == "Options" {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonError adds the line and the column of the failure to err
+func jsonError(b []byte, err error) error {
+	var offset int64
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	default:
+		return err
+	}
+	// the offset is right after the byte that failed
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+	if offset > 0 {
+		offset--
+	}
+	before := b[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return fmt.Errorf("line %d, column %d: %v", line, column, err)
 }
 
 func (si *SiteInformation) fillDefaultValues() {
@@ -77,4 +354,36 @@ func (si *SiteInformation) fillDefaultValues() {
 	if si.NumPostsFrontPage == 0 {
 		si.NumPostsFrontPage = 10
 	}
+	if si.Permalinks.Post == "" {
+		si.Permalinks.Post = "/:section/:slug/"
+	}
+	if si.Permalinks.Tag == "" {
+		si.Permalinks.Tag = "/tags/:slug/"
+	}
+	if si.Permalinks.Category == "" {
+		si.Permalinks.Category = "/categories/:slug/"
+	}
+}
+
+// Sources returns the datasources of the blog. When DataSources is
+// empty the single DataSource is used.
+func (si SiteInformation) Sources() []DataSource {
+	if len(si.DataSources) == 0 {
+		return []DataSource{si.DataSource}
+	}
+	return si.DataSources
+}
+
+// SourceFolder returns the folder the i-th datasource of Sources is
+// fetched into. The single DataSource is fetched into the TempFolder,
+// each one of DataSources into its own folder inside it.
+func (si SiteInformation) SourceFolder(i int) string {
+	if len(si.DataSources) == 0 {
+		return si.TempFolder
+	}
+	name := si.DataSources[i].Name
+	if name == "" {
+		name = fmt.Sprintf("source%d", i+1)
+	}
+	return filepath.Join(si.TempFolder, name)
 }
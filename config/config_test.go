@@ -1,6 +1,9 @@
 package config_test
 
 import (
+	"encoding/json"
+	"os"
+	"strings"
 	"testing"
 	"github.com/RomanosTrechlis/blog-gen/config"
 	"path/filepath"
@@ -14,6 +17,8 @@ func TestNew(t *testing.T) {
 		{filepath.Join("testdata", "config.json"), false},
 		{filepath.Join("testdata", "nofile.json"), true},
 		{filepath.Join("testdata", "configFillValues.json"), false},
+		{filepath.Join("testdata", "config.yml"), false},
+		{filepath.Join("testdata", "config.toml"), false},
 	}
 
 	for _, tt := range tests {
@@ -43,3 +48,213 @@ func TestNew(t *testing.T) {
 		}
 	}
 }
+
+func TestSourceFolder(t *testing.T) {
+	s := config.SiteInformation{TempFolder: "tmp", DataSource: config.DataSource{Type: "local"}}
+	if len(s.Sources()) != 1 || s.Sources()[0].Type != "local" {
+		t.Errorf("expected the single datasource, got %v", s.Sources())
+	}
+	if s.SourceFolder(0) != "tmp" {
+		t.Errorf("expected folder to be 'tmp', got '%s'", s.SourceFolder(0))
+	}
+
+	s.DataSources = []config.DataSource{{Name: "blog"}, {}}
+	var tests = []struct {
+		i      int
+		folder string
+	}{
+		{0, filepath.Join("tmp", "blog")},
+		{1, filepath.Join("tmp", "source2")},
+	}
+	for _, tt := range tests {
+		if s.SourceFolder(tt.i) != tt.folder {
+			t.Errorf("expected folder %d to be '%s', got '%s'", tt.i, tt.folder, s.SourceFolder(tt.i))
+		}
+	}
+}
+
+func TestRawOptions(t *testing.T) {
+	ds := config.DataSource{
+		Type:    "git",
+		Ref:     "v1",
+		Auth:    config.Auth{TokenEnv: "TOKEN"},
+		Options: map[string]interface{}{"Depth": 1.0},
+	}
+	options := ds.RawOptions()
+	if options["Ref"] != "v1" || options["Depth"] != 1.0 {
+		t.Errorf("expected Ref and Depth in options, got %v", options)
+	}
+	if _, ok := options["Subdirectory"]; ok {
+		t.Errorf("expected no empty settings in options, got %v", options)
+	}
+	auth, ok := options["Auth"].(map[string]interface{})
+	if !ok || auth["TokenEnv"] != "TOKEN" {
+		t.Errorf("expected TokenEnv in auth options, got %v", options["Auth"])
+	}
+	if _, ok := ds.Options["Ref"]; ok {
+		t.Errorf("expected Options of the datasource to be unchanged")
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	var tests = []struct {
+		file string
+		msg  string
+	}{
+		{filepath.Join("testdata", "syntax.json"), "line 3, column 13"},
+		{filepath.Join("testdata", "unknown.json"), "unknown field \"Autor\""},
+		{filepath.Join("testdata", "type.json"), "line 3, column 27"},
+		{filepath.Join("testdata", "unknown.yml"), "field Autor not found"},
+		{filepath.Join("testdata", "unknown.toml"), "unknown field \"Autor\""},
+	}
+
+	for _, tt := range tests {
+		_, err := config.New(tt.file)
+		if err == nil {
+			t.Errorf("%s: expected error, got no error", tt.file)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: expected error to contain '%s', got '%v'", tt.file, tt.msg, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s, err := config.New(filepath.Join("testdata", "config.json"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s.ThemeFolder = filepath.Join("testdata", "nofolder")
+	err = s.Validate()
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	s.BlogURL = "romanostrechlis.github.io"
+	s.DateFormat = "15:04"
+	s.DataSource.Type = "svn"
+	s.Theme.Type = ""
+	s.ThemeFolder = "testdata"
+	s.Permalinks.Post = ":year/:month/"
+	s.KeepBuilds = -1
+	err = s.Validate()
+	verr, ok := err.(*config.ValidationError)
+	if !ok {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	// 6 fields and the 6 static pages that are not in testdata
+	if len(verr.Problems) != 12 {
+		t.Errorf("expected 12 problems, got %d: %v", len(verr.Problems), verr)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	expected, err := config.New(filepath.Join("testdata", "config.json"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, file := range []string{"config.yml", "config.toml"} {
+		s, err := config.New(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", file, err)
+		}
+		if s.BlogURL != expected.BlogURL || s.DateFormat != expected.DateFormat ||
+			s.DataSource.Repository != expected.DataSource.Repository || s.Upload.URL != expected.Upload.URL {
+			t.Errorf("%s: expected the same configuration as config.json, got %+v", file, s)
+		}
+		if len(s.StaticPages) != 1 || !s.StaticPages[0].IsTemplate {
+			t.Errorf("%s: expected one template static page, got %v", file, s.StaticPages)
+		}
+		auth, ok := s.DataSource.Options["Auth"].(map[string]interface{})
+		if !ok || auth["TokenEnv"] != "BLOG_TOKEN" {
+			t.Errorf("%s: expected nested options, got %#v", file, s.DataSource.Options)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	var tests = []struct {
+		dir  string
+		file string
+	}{
+		{filepath.Join("testdata", "find"), filepath.Join("testdata", "find", "blog-gen.json")},
+		{filepath.Join("testdata", "find", "a", "b"), filepath.Join("testdata", "find", "blog-gen.json")},
+	}
+
+	for _, tt := range tests {
+		path, err := config.Find(tt.dir)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		expected, _ := filepath.Abs(tt.file)
+		if path != expected {
+			t.Errorf("expected '%s', got '%s'", expected, path)
+		}
+	}
+}
+
+func TestNewProfile(t *testing.T) {
+	s, err := config.NewProfile(filepath.Join("testdata", "config.json"), "staging")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if s.BlogURL != "https://staging.romanostrechlis.github.io" || s.Upload.Branch != "staging" {
+		t.Errorf("expected the fields of the profile, got %s and %s", s.BlogURL, s.Upload.Branch)
+	}
+	if s.Upload.URL == "" || s.Author != "Romanos Trechlis" {
+		t.Errorf("expected the fields of the base configuration to be kept")
+	}
+
+	_, err = config.NewProfile(filepath.Join("testdata", "config.json"), "production")
+	if err == nil {
+		t.Errorf("expected error for missing profile, got no error")
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	env := map[string]string{
+		"BLOGGEN_UPLOAD_PASSWORD":          "secret",
+		"BLOGGEN_NUMPOSTSFRONTPAGE":        "5",
+		"BLOGGEN_DATASOURCE_TAGS":          "go, web",
+		"BLOGGEN_STATICPAGES_0_ISTEMPLATE": "true",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	s, err := config.New(filepath.Join("testdata", "config.json"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if s.Upload.Password != "secret" || s.NumPostsFrontPage != 5 || !s.StaticPages[0].IsTemplate {
+		t.Errorf("expected the fields of the environment, got %+v", s)
+	}
+	if len(s.DataSource.Tags) != 2 || s.DataSource.Tags[1] != "web" {
+		t.Errorf("expected tags [go web], got %v", s.DataSource.Tags)
+	}
+
+	os.Setenv("BLOGGEN_NUMPOSTSFRONTPAGE", "five")
+	_, err = config.New(filepath.Join("testdata", "config.json"))
+	if err == nil || strings.Contains(err.Error(), "five") {
+		t.Errorf("expected error without the value, got %v", err)
+	}
+}
+
+func TestMasked(t *testing.T) {
+	s := config.SiteInformation{
+		Upload:      config.Upload{Password: "secret", Options: map[string]interface{}{"APIToken": "token"}},
+		DataSources: []config.DataSource{{Options: map[string]interface{}{"Auth": map[string]interface{}{"Password": "pass"}}}},
+	}
+	m := s.Masked()
+	b, _ := json.Marshal(m)
+	for _, secret := range []string{"secret", "token", "pass"} {
+		if strings.Contains(string(b), "\""+secret+"\"") {
+			t.Errorf("expected %s to be masked, got %s", secret, b)
+		}
+	}
+	if s.Upload.Password != "secret" || s.Upload.Options["APIToken"] != "token" {
+		t.Errorf("expected the original configuration to be unchanged")
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the names of the environment variables that override
+// the configuration, e.g. BLOGGEN_UPLOAD_PASSWORD for Upload.Password.
+const EnvPrefix = "BLOGGEN_"
+
+// mask replaces the secrets of the configuration when it is shown
+const mask = "*****"
+
+// ProfileFile returns the file of the profile env that overlays configFile,
+// e.g. blog-gen.staging.yml for blog-gen.yml.
+func ProfileFile(configFile, env string) string {
+	ext := filepath.Ext(configFile)
+	return strings.TrimSuffix(configFile, ext) + "." + env + ext
+}
+
+// applyEnv overrides the fields of si with the environment variables of
+// environ that are named after them. Fields of a list are named after
+// their index, e.g. BLOGGEN_DATASOURCES_0_REPOSITORY.
+func (si *SiteInformation) applyEnv(environ []string) error {
+	vars := make(map[string]string)
+	for _, kv := range environ {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 && strings.HasPrefix(parts[0], EnvPrefix) {
+			vars[parts[0]] = parts[1]
+		}
+	}
+	if len(vars) == 0 {
+		return nil
+	}
+	return applyEnvValue(reflect.ValueOf(si).Elem(), strings.TrimSuffix(EnvPrefix, "_"), vars)
+}
+
+func applyEnvValue(v reflect.Value, name string, vars map[string]string) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := name + "_" + strings.ToUpper(t.Field(i).Name)
+			err := applyEnvValue(v.Field(i), field, vars)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			for i := 0; i < v.Len(); i++ {
+				err := applyEnvValue(v.Index(i), fmt.Sprintf("%s_%d", name, i), vars)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	value, ok := vars[name]
+	if !ok {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("environment variable %s must be a number", name)
+		}
+		v.SetInt(int64(i))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("environment variable %s must be true or false", name)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("environment variable %s cannot override a list", name)
+		}
+		list := make([]string, 0)
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("environment variable %s cannot override a %s", name, v.Kind())
+	}
+	return nil
+}
+
+// Masked returns a copy of si with its secrets replaced, so that it
+// can be shown. Secrets are the fields tagged with secret:"true" and the
+// options with a name like a password or a token.
+func (si SiteInformation) Masked() SiteInformation {
+	v := reflect.New(reflect.TypeOf(si)).Elem()
+	v.Set(maskValue(reflect.ValueOf(si)))
+	return v.Interface().(SiteInformation)
+}
+
+func maskValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			f := c.Field(i)
+			if v.Type().Field(i).Tag.Get("secret") == "true" {
+				if f.Kind() == reflect.String && f.String() != "" {
+					f.SetString(mask)
+				}
+				continue
+			}
+			f.Set(maskValue(f))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() != reflect.Struct {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(maskValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if m, ok := v.Interface().(map[string]interface{}); ok && m != nil {
+			return reflect.ValueOf(maskOptions(m))
+		}
+	}
+	return v
+}
+
+// maskOptions returns a copy of options with the secret values replaced
+func maskOptions(options map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(options))
+	for k, v := range options {
+		if isSecretName(k) {
+			c[k] = mask
+			continue
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			v = maskOptions(m)
+		}
+		c[k] = v
+	}
+	return c
+}
+
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"password", "secret", "token"} {
+		if strings.Contains(name, s) && !strings.HasSuffix(name, "env") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/RomanosTrechlis/blog-gen/datasource"
+	"github.com/RomanosTrechlis/blog-gen/endpoint"
+)
+
+// ValidationError holds all the problems found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration:\n\t%s", strings.Join(e.Problems, "\n\t"))
+}
+
+// Validate checks the fields the blog cannot be generated without.
+// It returns a *ValidationError with every problem found, or nil.
+func (si SiteInformation) Validate() error {
+	var problems []string
+	add := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	u, err := url.Parse(si.BlogURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		add("BlogURL '%s' must be an absolute url with a scheme, e.g. https://example.com", si.BlogURL)
+	}
+	if !isDateFormat(si.DateFormat) {
+		add("DateFormat '%s' must hold a year, a month and a day, e.g. 2006-01-02 15:04:05", si.DateFormat)
+	}
+	if si.NumPostsFrontPage < 0 {
+		add("NumPostsFrontPage must be positive, found %d", si.NumPostsFrontPage)
+	}
+	if si.KeepBuilds < 0 {
+		add("KeepBuilds must be positive, found %d", si.KeepBuilds)
+	}
+
+	checkPermalink(add, "Permalinks.Post", si.Permalinks.Post)
+	checkPermalink(add, "Permalinks.Tag", si.Permalinks.Tag)
+	checkPermalink(add, "Permalinks.Category", si.Permalinks.Category)
+
+	dsTypes := datasource.Types()
+	if len(si.DataSources) == 0 {
+		checkType(add, "DataSource.Type", si.DataSource.Type, dsTypes, true)
+	}
+	for i, ds := range si.DataSources {
+		checkType(add, fmt.Sprintf("DataSources[%d].Type", i), ds.Type, dsTypes, true)
+	}
+	checkType(add, "Theme.Type", si.Theme.Type, dsTypes, true)
+	checkType(add, "Upload.Type", si.Upload.Type, endpoint.Types(), false)
+
+	// static pages are checked only after the theme is fetched
+	if _, err := os.Stat(si.ThemeFolder); err == nil {
+		for _, page := range si.StaticPages {
+			path := filepath.Join(si.ThemeFolder, page.File)
+			if _, err := os.Stat(path); err != nil {
+				add("StaticPages file '%s' doesn't exist in %s", page.File, si.ThemeFolder)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// checkType adds a problem when value is not one of the registered types
+func checkType(add func(string, ...interface{}), field, value string, types []string, required bool) {
+	if value == "" {
+		if required {
+			add("%s is required, one of: %s", field, strings.Join(types, ", "))
+		}
+		return
+	}
+	for _, t := range types {
+		if t == value {
+			return
+		}
+	}
+	add("%s '%s' is unknown, one of: %s", field, value, strings.Join(types, ", "))
+}
+
+// checkPermalink adds a problem when pattern cannot give every page its own url
+func checkPermalink(add func(string, ...interface{}), field, pattern string) {
+	if !strings.HasPrefix(pattern, "/") || !strings.Contains(pattern, ":slug") {
+		add("%s '%s' must start with / and contain :slug", field, pattern)
+	}
+}
+
+// isDateFormat reports whether a date formatted with layout can be
+// parsed back without losing its day.
+func isDateFormat(layout string) bool {
+	date := time.Date(2009, time.November, 17, 20, 34, 58, 0, time.UTC)
+	s := date.Format(layout)
+	if layout == "" || s == layout {
+		return false
+	}
+	parsed, err := time.Parse(layout, s)
+	if err != nil {
+		return false
+	}
+	return parsed.Format(layout) == s && parsed.Year() == date.Year() && parsed.YearDay() == date.YearDay()
+}
@@ -0,0 +1,461 @@
+package datasource
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/RomanosTrechlis/blog-gen/util/fs"
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
+)
+
+// archiveDataSource fetches the posts from a zip, tar or tar.gz file,
+// found in a local path or an http(s) url
+type archiveDataSource struct {
+	logged
+	subdirectory    string
+	stripComponents int
+}
+
+// newArchiveDataSource creates a new archiveDataSource
+func newArchiveDataSource(opts Options) (ds DataSource) {
+	return &archiveDataSource{
+		subdirectory:    strings.Trim(path.Clean("/"+filepath.ToSlash(opts.Subdirectory)), "/"),
+		stripComponents: opts.StripComponents,
+	}
+}
+
+// Fetch clears the output folder and extracts the archive there
+func (ds *archiveDataSource) Fetch(from, to string) (dirs []string, err error) {
+	logger.Infof(ds.log, "Fetching data from %s into %s...", redactURL(from), to)
+	format, err := archiveFormat(from)
+	if err != nil {
+		return nil, err
+	}
+	file := from
+	if isRemote(from) {
+		file, err = download(from)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(file)
+	}
+
+	err = fs.CreateFolderIfNotExist(to)
+	if err != nil {
+		return nil, err
+	}
+	err = fs.ClearFolder(to)
+	if err != nil {
+		return nil, err
+	}
+	x := &extractor{root: to, subdirectory: ds.subdirectory, stripComponents: ds.stripComponents}
+	switch format {
+	case ".zip":
+		err = x.extractZip(file)
+	case ".tar":
+		err = x.extractTar(file, false)
+	default:
+		err = x.extractTar(file, true)
+	}
+	if err == nil {
+		err = x.checkLinks()
+	}
+	if err != nil {
+		// nothing is left behind that could point outside of the folder
+		fs.ClearFolder(to)
+		return nil, fmt.Errorf("failed to extract %s: %v", redactURL(from), err)
+	}
+	dirs, err = fs.GetContent(to)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf(ds.log, "Fetching complete.")
+	return dirs, nil
+}
+
+// archiveFormat returns the format of the archive from its extension
+func archiveFormat(location string) (string, error) {
+	name := strings.ToLower(location)
+	if isRemote(name) {
+		name = strings.SplitN(strings.SplitN(name, "?", 2)[0], "#", 2)[0]
+	}
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return ".zip", nil
+	case strings.HasSuffix(name, ".tar"):
+		return ".tar", nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ".tar.gz", nil
+	}
+	return "", fmt.Errorf("unknown archive format of %s, expected .zip, .tar or .tar.gz", redactURL(location))
+}
+
+func isRemote(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// download saves the archive of url in a temporary file and returns its path
+func download(url string) (string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download archive: %v", redactURLs(err.Error()))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download archive %s: %s", redactURL(url), resp.Status)
+	}
+
+	f, err := ioutil.TempFile("", "blog-gen-archive")
+	if err != nil {
+		return "", fmt.Errorf("error creating temporary file: %v", err)
+	}
+	_, err = io.Copy(f, resp.Body)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to download archive %s: %v", redactURL(url), err)
+	}
+	return f.Name(), nil
+}
+
+// extractor writes the entries of an archive inside root. Entries that
+// would end up outside of root are rejected.
+type extractor struct {
+	root            string
+	subdirectory    string
+	stripComponents int
+	// links are the symlinks created so far, relative to root
+	links []string
+}
+
+func (x *extractor) extractZip(file string) error {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		name, err := x.target(f.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		mode := f.Mode()
+		if mode.IsDir() {
+			err = x.createDir(name)
+		} else {
+			var rc io.ReadCloser
+			rc, err = f.Open()
+			if err != nil {
+				return fmt.Errorf("error reading %s: %v", f.Name, err)
+			}
+			if mode&os.ModeSymlink != 0 {
+				var b []byte
+				b, err = ioutil.ReadAll(rc)
+				if err == nil {
+					err = x.createSymlink(name, string(b))
+				}
+			} else if mode.IsRegular() {
+				err = x.createFile(name, rc, mode)
+			}
+			rc.Close()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) extractTar(file string, gzipped bool) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, err := x.target(h.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = x.createDir(name)
+		case tar.TypeReg, tar.TypeRegA:
+			err = x.createFile(name, tr, h.FileInfo().Mode())
+		case tar.TypeSymlink:
+			err = x.createSymlink(name, h.Linkname)
+		case tar.TypeLink:
+			err = x.createLink(name, h.Linkname)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// target returns the path of an entry relative to root, after removing
+// the stripped components and the subdirectory. An empty path means
+// that the entry is skipped.
+func (x *extractor) target(name string) (string, error) {
+	name = strings.Replace(name, "\\", "/", -1)
+	if path.IsAbs(name) || hasParentRef(name) {
+		return "", fmt.Errorf("archive entry %s points outside of the target folder", name)
+	}
+	parts := strings.Split(path.Clean(name), "/")
+	if len(parts) <= x.stripComponents {
+		return "", nil
+	}
+	rel := strings.Join(parts[x.stripComponents:], "/")
+	if x.subdirectory != "" {
+		if rel != x.subdirectory && !strings.HasPrefix(rel, x.subdirectory+"/") {
+			return "", nil
+		}
+		rel = strings.TrimPrefix(strings.TrimPrefix(rel, x.subdirectory), "/")
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return rel, nil
+}
+
+// hasParentRef reports whether a slash separated path contains a ".." element
+func hasParentRef(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// path returns the full path of rel and makes sure that none of its
+// parents inside root is a symlink, so nothing is written through a link.
+func (x *extractor) path(rel string) (string, error) {
+	parts := strings.Split(rel, "/")
+	p := x.root
+	for _, part := range parts[:len(parts)-1] {
+		p = filepath.Join(p, part)
+		info, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("archive entry %s is written through a symlink", rel)
+		}
+	}
+	return filepath.Join(x.root, filepath.FromSlash(rel)), nil
+}
+
+func (x *extractor) createDir(rel string) error {
+	p, err := x.path(rel)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(p, os.ModePerm)
+}
+
+func (x *extractor) createFile(rel string, r io.Reader, mode os.FileMode) error {
+	p, err := x.path(rel)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(p), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	// a file replaces a symlink with the same name instead of writing through it
+	if info, err := os.Lstat(p); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		err = os.Remove(p)
+		if err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// createSymlink creates a symlink, as long as it points inside root. The
+// target is resolved through the symlinks that are already extracted.
+func (x *extractor) createSymlink(rel, link string) error {
+	link = strings.Replace(link, "\\", "/", -1)
+	if path.IsAbs(link) || !isInsideRoot(path.Join(path.Dir(rel), link)) {
+		return fmt.Errorf("archive entry %s links to %s outside of the target folder", rel, link)
+	}
+	err := x.resolve(path.Dir(rel) + "/" + link)
+	if err != nil {
+		return fmt.Errorf("archive entry %s links to %s outside of the target folder", rel, link)
+	}
+	p, err := x.path(rel)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(p), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = os.Symlink(filepath.FromSlash(link), p)
+	if err != nil {
+		return err
+	}
+	x.links = append(x.links, rel)
+	return nil
+}
+
+// checkLinks makes sure that every symlink still points inside root once
+// all the entries are extracted, since a later entry can change where
+// an earlier link leads.
+func (x *extractor) checkLinks() error {
+	for _, rel := range x.links {
+		err := x.resolve(rel)
+		if err != nil {
+			return fmt.Errorf("archive entry %s links outside of the target folder", rel)
+		}
+	}
+	return nil
+}
+
+// maxLinkHops limits the symlinks followed by resolve, so link loops end
+const maxLinkHops = 40
+
+// resolve follows the slash separated rel from root through the symlinks
+// that exist on disk, without cleaning ".." before the links are
+// followed. The parts that do not exist yet are kept as they are. It
+// fails when the path leaves root.
+func (x *extractor) resolve(rel string) error {
+	hops := 0
+	var walk func(dir []string, rel string) ([]string, error)
+	walk = func(dir []string, rel string) ([]string, error) {
+		cur := append([]string(nil), dir...)
+		for _, part := range strings.Split(rel, "/") {
+			switch part {
+			case "", ".":
+				continue
+			case "..":
+				if len(cur) == 0 {
+					return nil, fmt.Errorf("%s leaves the target folder", rel)
+				}
+				cur = cur[:len(cur)-1]
+				continue
+			}
+			p := filepath.Join(x.root, filepath.Join(cur...), part)
+			info, err := os.Lstat(p)
+			if err != nil || info.Mode()&os.ModeSymlink == 0 {
+				cur = append(cur, part)
+				continue
+			}
+			hops++
+			if hops > maxLinkHops {
+				return nil, fmt.Errorf("too many links in %s", rel)
+			}
+			link, err := os.Readlink(p)
+			if err != nil {
+				return nil, err
+			}
+			link = filepath.ToSlash(link)
+			if path.IsAbs(link) || filepath.IsAbs(link) {
+				return nil, fmt.Errorf("%s links to an absolute path", rel)
+			}
+			cur, err = walk(cur, link)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return cur, nil
+	}
+	_, err := walk(nil, rel)
+	return err
+}
+
+// createLink creates a hard link to an entry that is already extracted.
+// A link to a symlink is checked like the symlinks.
+func (x *extractor) createLink(rel, link string) error {
+	target, err := x.target(link)
+	if err != nil {
+		return err
+	}
+	if target == "" {
+		return fmt.Errorf("archive entry %s links to %s outside of the target folder", rel, link)
+	}
+	src, err := x.path(target)
+	if err != nil {
+		return err
+	}
+	p, err := x.path(rel)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(p), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = os.Link(src, p)
+	if err != nil {
+		return err
+	}
+	// a hard link to a symlink is a symlink that resolves from rel
+	info, err := os.Lstat(p)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		err = x.resolve(rel)
+		if err != nil {
+			return fmt.Errorf("archive entry %s links outside of the target folder", rel)
+		}
+		x.links = append(x.links, rel)
+	}
+	return nil
+}
+
+// isInsideRoot reports whether a path relative to root stays inside it
+func isInsideRoot(rel string) bool {
+	rel = path.Clean(rel)
+	return rel != ".." && !strings.HasPrefix(rel, "../")
+}
@@ -0,0 +1,205 @@
+package datasource
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// entry is a file, folder or symlink of a test archive
+type entry struct {
+	name, body, link string
+	// hard makes link a hard link
+	dir, hard bool
+}
+
+func writeTarGz(t *testing.T, path string, entries []entry) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		h := &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(e.body))}
+		if e.dir {
+			h = &tar.Header{Name: e.name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if e.link != "" {
+			h = &tar.Header{Name: e.name, Mode: 0777, Typeflag: tar.TypeSymlink, Linkname: e.link}
+		}
+		if e.hard {
+			h = &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeLink, Linkname: e.link}
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, path string, entries []entry) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		h := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		body := e.body
+		if e.link != "" {
+			h.SetMode(os.ModeSymlink | 0777)
+			body = e.link
+		}
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	zw.Close()
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveFetch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	posts := []entry{
+		{name: "blog-1.0/", dir: true},
+		{name: "blog-1.0/first/post.md", body: "first"},
+		{name: "blog-1.0/first/link.md", link: "post.md"},
+		{name: "blog-1.0/note.md", body: "note"},
+	}
+	writeTarGz(t, filepath.Join(dir, "posts.tar.gz"), posts)
+	writeZip(t, filepath.Join(dir, "posts.zip"), posts)
+	writeTarGz(t, filepath.Join(dir, "traversal.tgz"), []entry{{name: "../evil.md", body: "evil"}})
+	writeZip(t, filepath.Join(dir, "traversal.zip"), []entry{{name: "a/../../evil.md", body: "evil"}})
+	writeTarGz(t, filepath.Join(dir, "symlink.tar.gz"), []entry{{name: "passwd", link: "/etc/passwd"}})
+	writeZip(t, filepath.Join(dir, "symlink.zip"), []entry{{name: "a/up", link: "../.."}})
+	// the chained links lead from the target folder to this file
+	err = ioutil.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTarGz(t, filepath.Join(dir, "chained.tar.gz"), []entry{
+		{name: "d", link: "."},
+		{name: "e", link: "d/.."},
+		{name: "p/post.md", link: "../e/secret.txt"},
+	})
+	writeTarGz(t, filepath.Join(dir, "reordered.tar.gz"), []entry{
+		{name: "p/post.md", link: "../e/secret.txt"},
+		{name: "e", link: "d/.."},
+		{name: "d", link: "."},
+	})
+	writeTarGz(t, filepath.Join(dir, "hardlink.tar.gz"), []entry{
+		{name: "a/b/link", link: "../.."},
+		{name: "esc", link: "a/b/link", hard: true},
+	})
+	writeTarGz(t, filepath.Join(dir, "through.tar.gz"), []entry{
+		{name: "here", link: "."},
+		{name: "here/file.md", body: "through"},
+	})
+
+	tests := []struct {
+		file  string
+		strip int
+		err   bool
+		num   int
+	}{
+		{"posts.tar.gz", 1, false, 2},
+		{"posts.zip", 1, false, 2},
+		{"posts.tar.gz", 0, false, 1},
+		{"traversal.tgz", 0, true, 0},
+		{"traversal.zip", 0, true, 0},
+		{"symlink.tar.gz", 0, true, 0},
+		{"symlink.zip", 0, true, 0},
+		{"through.tar.gz", 0, true, 0},
+		{"chained.tar.gz", 0, true, 0},
+		{"reordered.tar.gz", 0, true, 0},
+		{"hardlink.tar.gz", 0, true, 0},
+		{"posts.rar", 0, true, 0},
+	}
+
+	for _, tt := range tests {
+		to := filepath.Join(dir, "out")
+		ds := newArchiveDataSource(Options{StripComponents: tt.strip})
+		dirs, err := ds.Fetch(filepath.Join(dir, tt.file), to)
+		if err != nil && !tt.err {
+			t.Errorf("%s: expected no error, got %v", tt.file, err)
+		}
+		if err == nil && tt.err {
+			t.Errorf("%s: expected error, got no error", tt.file)
+		}
+		if len(dirs) != tt.num {
+			t.Errorf("%s: expected %d posts, got %d", tt.file, tt.num, len(dirs))
+		}
+		if _, err := os.Stat(filepath.Join(dir, "evil.md")); err == nil {
+			t.Fatalf("%s: file was written outside of the target folder", tt.file)
+		}
+		if _, err := ioutil.ReadFile(filepath.Join(to, "p", "post.md")); err == nil {
+			t.Fatalf("%s: file outside of the target folder can be read", tt.file)
+		}
+	}
+}
+
+func TestArchiveFetchSubdirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTarGz(t, filepath.Join(dir, "site.tar.gz"), []entry{
+		{name: "site/README.md", body: "readme"},
+		{name: "site/content/first/post.md", body: "first"},
+		{name: "site/content/second.md", body: "second"},
+	})
+	ds := newArchiveDataSource(Options{StripComponents: 1, Subdirectory: "content"})
+	dirs, err := ds.Fetch(filepath.Join(dir, "site.tar.gz"), filepath.Join(dir, "out"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(dirs) != 2 {
+		t.Errorf("expected 2 posts, got %v", dirs)
+	}
+}
+
+func TestArchiveFetchURL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeZip(t, filepath.Join(dir, "posts.zip"), []entry{{name: "first/post.md", body: "first"}})
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	ds := newArchiveDataSource(Options{})
+	dirs, err := ds.Fetch(server.URL+"/posts.zip?version=1", filepath.Join(dir, "out"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(dirs) != 1 {
+		t.Errorf("expected 1 post, got %v", dirs)
+	}
+	_, err = ds.Fetch(server.URL+"/missing.zip", filepath.Join(dir, "out"))
+	if err == nil {
+		t.Errorf("expected error for missing archive, got no error")
+	}
+}
@@ -0,0 +1,93 @@
+package datasource
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// defaultTokenUsername is the user name sent together with a token
+const defaultTokenUsername = "x-access-token"
+
+var (
+	envNamePattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._@+-]+$`)
+	userinfoPattern = regexp.MustCompile(`(://)[^/@\s]+@`)
+)
+
+// Auth holds the credentials of a private git repository. Only one
+// of SSHKey, TokenEnv and CredentialHelper is expected to be set.
+type Auth struct {
+	// SSHKey is the path of the private key used for ssh urls
+	SSHKey string
+	// TokenEnv is the name of the environment variable
+	// that holds the token used for https urls
+	TokenEnv string
+	// Username is sent together with the token
+	Username string
+	// CredentialHelper is the git credential helper used for https urls
+	CredentialHelper string
+}
+
+// gitConfig returns the git arguments and the environment variables that
+// pass the credentials to git. Secrets are never part of the arguments,
+// so they are not written to the git configuration or shown in errors.
+func (a Auth) gitConfig() (args, env []string, err error) {
+	// fail instead of waiting for a password that will never be typed
+	env = []string{"GIT_TERMINAL_PROMPT=0"}
+	if a.SSHKey != "" {
+		_, err = os.Stat(a.SSHKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error accessing ssh key %s: %v", a.SSHKey, err)
+		}
+		env = append(env, fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %s -o IdentitiesOnly=yes", shellQuote(a.SSHKey)))
+	}
+
+	if a.TokenEnv != "" {
+		if !envNamePattern.MatchString(a.TokenEnv) {
+			return nil, nil, fmt.Errorf("'%s' is not a valid environment variable name", a.TokenEnv)
+		}
+		if os.Getenv(a.TokenEnv) == "" {
+			return nil, nil, fmt.Errorf("environment variable %s with the token is not set", a.TokenEnv)
+		}
+		username := a.Username
+		if username == "" {
+			username = defaultTokenUsername
+		}
+		if !usernamePattern.MatchString(username) {
+			return nil, nil, fmt.Errorf("'%s' is not a valid user name", username)
+		}
+		// the helper reads the token from the environment when git asks for it
+		helper := fmt.Sprintf(`!f() { test "$1" = get && echo "username=%s" && echo "password=$%s"; }; f`,
+			username, a.TokenEnv)
+		args = append(args, "-c", "credential.helper=", "-c", "credential.helper="+helper)
+	} else if a.CredentialHelper != "" {
+		args = append(args, "-c", "credential.helper=", "-c", "credential.helper="+a.CredentialHelper)
+	}
+	return args, env, nil
+}
+
+// shellQuote quotes s for the shell that runs GIT_SSH_COMMAND
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// redactURL hides the password of a url, so that it can be logged
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	if _, ok := u.User.Password(); !ok {
+		return rawURL
+	}
+	u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	return u.String()
+}
+
+// redactURLs hides the credentials of all the urls inside s
+func redactURLs(s string) string {
+	return userinfoPattern.ReplaceAllString(s, "${1}xxxxx@")
+}
@@ -1,7 +1,12 @@
 package datasource
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
 )
 
 // DataSource fetches data from an endpoint
@@ -9,15 +14,113 @@ type DataSource interface {
 	Fetch(from, to string) ([]string, error)
 }
 
-// New is a data source factory
-func New(dsType string) (ds DataSource, err error) {
-	switch dsType {
-	case "git":
-		ds = newGitDataSource()
-	case "local":
-		ds = newLocalDataSource()
-	case "":
-		err = fmt.Errorf("please provide a datasource in the configuration file")
+// Factory creates a data source from the options of the configuration
+// file. The options hold the type specific settings of the data source.
+type Factory func(options map[string]interface{}) (DataSource, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+func init() {
+	Register("git", builtin(newGitDataSource))
+	Register("local", builtin(newLocalDataSource))
+	Register("archive", builtin(newArchiveDataSource))
+}
+
+// Register makes a data source type available to New. It panics if
+// name is empty, factory is nil or name is already registered.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if name == "" || factory == nil {
+		panic("datasource: Register needs a name and a factory")
+	}
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("datasource: Register called twice for type '%s'", name))
+	}
+	factories[name] = factory
+}
+
+// Types returns the sorted names of the registered data source types
+func Types() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	types := make([]string, 0, len(factories))
+	for name := range factories {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Options holds the settings of the built-in data sources besides their
+// location. They are read from the keys of the same name in the options
+// given to New.
+type Options struct {
+	// Ref is the branch, tag or commit to fetch. It is used by the git
+	// data source and defaults to the default branch of the repository.
+	Ref string
+	// Subdirectory is the part of the repository that is fetched
+	Subdirectory string
+	// Auth holds the credentials of private git repositories
+	Auth Auth
+	// StripComponents is the number of leading folders removed from
+	// the entries of an archive
+	StripComponents int
+}
+
+// New is a data source factory. It creates a data source of the
+// registered dsType with the given options.
+func New(dsType string, options map[string]interface{}) (ds DataSource, err error) {
+	if dsType == "" {
+		return nil, fmt.Errorf("please provide a datasource in the configuration file")
+	}
+	factoriesMu.RLock()
+	factory, ok := factories[dsType]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown datasource type '%s'", dsType)
+	}
+	ds, err = factory(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s datasource: %v", dsType, err)
+	}
+	return ds, nil
+}
+
+// SetLogger sends the messages of ds to log. The data sources that log
+// their progress have a SetLogger(logger.Logger) method, the rest are
+// left as they are.
+func SetLogger(ds DataSource, log logger.Logger) {
+	if l, ok := ds.(interface{ SetLogger(logger.Logger) }); ok {
+		l.SetLogger(log)
+	}
+}
+
+// logged is embedded in the built-in data sources to log their progress
+type logged struct {
+	log logger.Logger
+}
+
+// SetLogger sends the messages of the data source to log
+func (l *logged) SetLogger(log logger.Logger) {
+	l.log = log
+}
+
+// builtin adapts the constructor of a built-in data source to a Factory
+func builtin(newDataSource func(Options) DataSource) Factory {
+	return func(options map[string]interface{}) (DataSource, error) {
+		var opts Options
+		b, err := json.Marshal(options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid options: %v", err)
+		}
+		err = json.Unmarshal(b, &opts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid options: %v", err)
+		}
+		return newDataSource(opts), nil
 	}
-	return ds, err
 }
@@ -0,0 +1,129 @@
+package datasource
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
+)
+
+// fakeDataSource is a data source registered by the tests
+type fakeDataSource struct {
+	options map[string]interface{}
+}
+
+func (ds *fakeDataSource) Fetch(from, to string) ([]string, error) {
+	return nil, nil
+}
+
+func TestRegister(t *testing.T) {
+	Register("fake", func(options map[string]interface{}) (DataSource, error) {
+		return &fakeDataSource{options: options}, nil
+	})
+
+	options := map[string]interface{}{"Bucket": "posts"}
+	ds, err := New("fake", options)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	fake, ok := ds.(*fakeDataSource)
+	if !ok {
+		t.Fatalf("expected the registered data source, got %T", ds)
+	}
+	if !reflect.DeepEqual(fake.options, options) {
+		t.Errorf("expected options %v, got %v", options, fake.options)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic when registering a type twice")
+		}
+	}()
+	Register("fake", func(options map[string]interface{}) (DataSource, error) {
+		return nil, nil
+	})
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		dsType  string
+		options map[string]interface{}
+		err     bool
+	}{
+		{"", nil, true},
+		{"unknown", nil, true},
+		{"local", nil, false},
+		{"archive", map[string]interface{}{"StripComponents": 1}, false},
+		{"archive", map[string]interface{}{"StripComponents": "one"}, true},
+	}
+
+	for _, tt := range tests {
+		_, err := New(tt.dsType, tt.options)
+		if err != nil && !tt.err {
+			t.Errorf("%s: expected no error, got %v", tt.dsType, err)
+		}
+		if err == nil && tt.err {
+			t.Errorf("%s: expected error, got no error", tt.dsType)
+		}
+	}
+
+	options := map[string]interface{}{
+		"Ref":          "v1",
+		"Subdirectory": "posts",
+		"Auth":         map[string]interface{}{"TokenEnv": "TOKEN"},
+	}
+	ds, err := New("git", options)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := &gitDataSource{ref: "v1", subdirectory: "posts", auth: Auth{TokenEnv: "TOKEN"}}
+	if !reflect.DeepEqual(ds, expected) {
+		t.Errorf("expected %+v, got %+v", expected, ds)
+	}
+}
+
+// recordLogger keeps the messages it receives
+type recordLogger struct {
+	messages []string
+}
+
+func (l *recordLogger) Log(level logger.Level, msg string, fields logger.Fields) {
+	l.messages = append(l.messages, level.String()+" "+msg)
+}
+
+func TestSetLogger(t *testing.T) {
+	root, err := ioutil.TempDir("", "blog-gen-logger")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+	from := filepath.Join(root, "posts")
+	err = os.MkdirAll(from, os.ModePerm)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	ds, err := New("local", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	log := &recordLogger{}
+	SetLogger(ds, log)
+	SetLogger(&fakeDataSource{}, log)
+	to := filepath.Join(root, "tmp")
+	_, err = ds.Fetch(from, to)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := []string{
+		fmt.Sprintf("info Fetching data from %s into %s...", from, to),
+		"debug Fetching complete.",
+	}
+	if !reflect.DeepEqual(log.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, log.messages)
+	}
+}
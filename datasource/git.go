@@ -1,67 +1,212 @@
 package datasource
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"github.com/RomanosTrechlis/blog-gen/util/fs"
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
 )
 
 // gitDataSource is the git data source object
-type gitDataSource struct{}
+type gitDataSource struct {
+	logged
+	ref          string
+	subdirectory string
+	auth         Auth
+}
 
 // newGitDataSource creates a new GitDataSource
-func newGitDataSource() (ds DataSource) {
-	return &gitDataSource{}
+func newGitDataSource(opts Options) (ds DataSource) {
+	return &gitDataSource{ref: opts.Ref, subdirectory: opts.Subdirectory, auth: opts.Auth}
 }
 
-// Fetch creates the output folder, clears it and clones the repository there
+// Fetch checks out the ref of the repository in the output folder. An
+// existing clone of the same repository is updated with a fetch, otherwise
+// the output folder is cleared and the repository is cloned there. A
+// failed update, e.g. of an unreachable remote or an unknown ref, is
+// returned and the existing clone is kept.
+// When a subdirectory is set, the repository is checked out in a sibling
+// folder and only the subdirectory is copied to the output folder.
 func (ds *gitDataSource) Fetch(from, to string) (dirs []string, err error) {
-	fmt.Printf("Fetching data from %s into %s...\n", from, to)
-	err = fs.CreateFolderIfNotExist(to)
+	logger.Infof(ds.log, "Fetching data from %s into %s...", redactURL(from), to)
+	repo := CheckoutFolder(to, ds.subdirectory)
+	err = fs.CreateFolderIfNotExist(repo)
 	if err != nil {
 		return nil, err
 	}
-	err = fs.ClearFolder(to)
-	if err != nil {
-		return nil, err
+
+	if ds.isClone(repo, from) {
+		err = ds.updateRepo(repo)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err = fs.ClearFolder(repo)
+		if err != nil {
+			return nil, err
+		}
+		err = ds.cloneRepo(repo, from)
+		if err != nil {
+			return nil, err
+		}
 	}
-	err = cloneRepo(to, from)
-	if err != nil {
-		return nil, err
+
+	if ds.subdirectory != "" {
+		err = copySubdirectory(repo, ds.subdirectory, to)
+		if err != nil {
+			return nil, err
+		}
 	}
-	dirs, err = fs.GetContentFolders(to)
+	dirs, err = fs.GetContent(to)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println("Fetching complete.")
+	logger.Debugf(ds.log, "Fetching complete.")
 	return dirs, nil
 }
 
-func cloneRepo(path, repositoryURL string) (err error) {
-	cmdName := "git"
-	initArgs := []string{"init", "."}
-	cmd := exec.Command(cmdName, initArgs...)
-	cmd.Dir = path
-	err = cmd.Run()
+// CheckoutFolder returns the folder a git data source with subdirectory
+// checks out its repository in, when it fetches into to
+func CheckoutFolder(to, subdirectory string) string {
+	if subdirectory == "" {
+		return to
+	}
+	return to + "_checkout"
+}
+
+// isClone reports whether path holds a healthy clone of repositoryURL
+func (ds *gitDataSource) isClone(path, repositoryURL string) bool {
+	_, err := os.Stat(filepath.Join(path, ".git"))
+	if err != nil {
+		return false
+	}
+	url, err := ds.git(path, "config", "--get", "remote.origin.url")
+	if err != nil || url != repositoryURL {
+		return false
+	}
+	_, err = ds.git(path, "status", "--porcelain")
+	return err == nil
+}
+
+func (ds *gitDataSource) cloneRepo(path, repositoryURL string) (err error) {
+	_, err = ds.git(path, "init", ".")
 	if err != nil {
 		return fmt.Errorf("error initializing git repository at %s: %v", path, err)
 	}
 
-	remoteArgs := []string{"remote", "add", "origin", repositoryURL}
-	cmd = exec.Command(cmdName, remoteArgs...)
-	cmd.Dir = path
-	err = cmd.Run()
+	_, err = ds.git(path, "remote", "add", "origin", repositoryURL)
 	if err != nil {
-		return fmt.Errorf("error setting remote %s: %v", repositoryURL, err)
+		return fmt.Errorf("error setting remote %s: %v", redactURL(repositoryURL), err)
 	}
+	return ds.updateRepo(path)
+}
 
-	pullArgs := []string{"pull", "origin", "master"}
-	cmd = exec.Command(cmdName, pullArgs...)
-	cmd.Dir = path
-	err = cmd.Run()
+// updateRepo fetches the changes of the remote and resets
+// the clone at path to the commit of the ref
+func (ds *gitDataSource) updateRepo(path string) (err error) {
+	_, err = ds.git(path, "fetch", "--tags", "--prune", "--force", "origin")
 	if err != nil {
-		return fmt.Errorf("error pulling master at %s: %v", path, err)
+		return fmt.Errorf("error fetching origin at %s: %v", path, err)
+	}
+
+	commit, err := ds.resolveRef(path, ds.ref)
+	if err != nil {
+		return err
+	}
+	_, err = ds.git(path, "checkout", "--force", "--detach", commit)
+	if err != nil {
+		return fmt.Errorf("error checking out %s at %s: %v", commit, path, err)
+	}
+	_, err = ds.git(path, "clean", "-ffdx")
+	if err != nil {
+		return fmt.Errorf("error cleaning %s: %v", path, err)
 	}
 	return nil
 }
+
+// resolveRef returns the commit of a branch, tag or commit of the fetched
+// repository at path. An empty ref is the default branch of the remote.
+func (ds *gitDataSource) resolveRef(path, ref string) (commit string, err error) {
+	if ref == "" {
+		_, err = ds.git(path, "remote", "set-head", "origin", "--auto")
+		if err != nil {
+			return "", fmt.Errorf("error finding the default branch at %s: %v", path, err)
+		}
+		ref = "origin/HEAD"
+	}
+
+	candidates := []string{"origin/" + ref, ref}
+	for _, candidate := range candidates {
+		commit, err = ds.git(path, "rev-parse", "--verify", "--quiet", candidate+"^{commit}")
+		if err == nil && commit != "" {
+			return commit, nil
+		}
+	}
+
+	// commits that are not part of a branch or a tag must be fetched by name
+	_, err = ds.git(path, "fetch", "origin", ref)
+	if err != nil {
+		return "", fmt.Errorf("couldn't find branch, tag or commit '%s': %v", ref, err)
+	}
+	commit, err = ds.git(path, "rev-parse", "--verify", "--quiet", "FETCH_HEAD^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("couldn't find branch, tag or commit '%s': %v", ref, err)
+	}
+	return commit, nil
+}
+
+// copySubdirectory replaces the contents of to with the subdirectory of repo
+func copySubdirectory(repo, subdirectory, to string) (err error) {
+	src := filepath.Join(repo, filepath.FromSlash(subdirectory))
+	rel, err := filepath.Rel(repo, src)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("subdirectory %s is outside of the repository", subdirectory)
+	}
+	info, err := os.Stat(src)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("subdirectory %s doesn't exist in the repository", subdirectory)
+	}
+
+	err = fs.CreateFolderIfNotExist(to)
+	if err != nil {
+		return err
+	}
+	err = fs.ClearFolder(to)
+	if err != nil {
+		return err
+	}
+	err = fs.CopyDir(src, to)
+	if err != nil {
+		return fmt.Errorf("error copying subdirectory %s to %s: %v", subdirectory, to, err)
+	}
+	return nil
+}
+
+// git runs a git command inside dir with the credentials
+// of the data source and returns its trimmed output
+func (ds *gitDataSource) git(dir string, args ...string) (string, error) {
+	authArgs, env, err := ds.auth.gitConfig()
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command("git", append(authArgs, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		msg := redactURLs(strings.TrimSpace(stderr.String()))
+		if msg == "" {
+			return "", err
+		}
+		return "", fmt.Errorf("%v: %s", err, msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
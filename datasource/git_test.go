@@ -0,0 +1,247 @@
+package datasource
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runGit runs a git command in dir for the tests, as a fixed author
+func runGit(t *testing.T, dir string, args ...string) string {
+	args = append([]string{"-c", "user.name=Tester", "-c", "user.email=tester@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commitFile writes a file of the work repository and commits it
+func commitFile(t *testing.T, work, name, content string) string {
+	path := filepath.Join(work, filepath.FromSlash(name))
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err == nil {
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+	}
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	runGit(t, work, "add", "-A")
+	runGit(t, work, "commit", "-q", "-m", "Add "+name)
+	return runGit(t, work, "rev-parse", "HEAD")
+}
+
+// gitRemote creates a bare repository in root whose default branch main
+// holds README.md and posts/first, and returns it with the work
+// repository that pushes to it
+func gitRemote(t *testing.T, root, name string) (remote, work string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	remote = filepath.Join(root, name+".git")
+	work = filepath.Join(root, name)
+	for _, dir := range []string{remote, work} {
+		err := os.MkdirAll(dir, os.ModePerm)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	runGit(t, remote, "init", "-q", "--bare")
+	runGit(t, remote, "symbolic-ref", "HEAD", "refs/heads/main")
+	runGit(t, work, "init", "-q")
+	runGit(t, work, "checkout", "-q", "-b", "main")
+	runGit(t, work, "remote", "add", "origin", remote)
+	commitFile(t, work, "README.md", "readme")
+	commitFile(t, work, "posts/first/post.md", "first")
+	runGit(t, work, "push", "-q", "origin", "main")
+	return remote, work
+}
+
+// expectFiles checks which of the names exist in dir
+func expectFiles(t *testing.T, dir string, exist map[string]bool) {
+	for name, expected := range exist {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if expected && err != nil {
+			t.Errorf("expected %s in %s, got %v", name, dir, err)
+		}
+		if !expected && err == nil {
+			t.Errorf("expected no %s in %s", name, dir)
+		}
+	}
+}
+
+func TestGitFetchUpdate(t *testing.T) {
+	root, err := ioutil.TempDir("", "blog-gen-git")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+	remote, work := gitRemote(t, root, "posts")
+	to := filepath.Join(root, "tmp")
+	// the marker survives as long as the clone is reused
+	marker := filepath.Join(to, ".git", "marker")
+
+	ds := newGitDataSource(Options{})
+	_, err = ds.Fetch(remote, to)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectFiles(t, to, map[string]bool{"README.md": true, "posts/first/post.md": true})
+
+	err = ioutil.WriteFile(marker, nil, 0644)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(to, "stray.md"), []byte("stray"), 0644)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	commitFile(t, work, "posts/second/post.md", "second")
+	runGit(t, work, "push", "-q", "origin", "main")
+	_, err = ds.Fetch(remote, to)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectFiles(t, to, map[string]bool{"posts/second/post.md": true, "stray.md": false, ".git/marker": true})
+
+	// a clone of another repository is replaced
+	other, _ := gitRemote(t, root, "other")
+	_, err = ds.Fetch(other, to)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectFiles(t, to, map[string]bool{"posts/first/post.md": true, "posts/second/post.md": false, ".git/marker": false})
+	url := runGit(t, to, "config", "--get", "remote.origin.url")
+	if url != other {
+		t.Errorf("expected remote %s, got %s", other, url)
+	}
+
+	// a broken clone is replaced
+	err = ioutil.WriteFile(marker, nil, 0644)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(to, ".git", "HEAD"), []byte("garbage"), 0644)
+	}
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, err = ds.Fetch(remote, to)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectFiles(t, to, map[string]bool{"posts/second/post.md": true, ".git/marker": false})
+
+	// a failed update keeps the clone
+	err = ioutil.WriteFile(marker, nil, 0644)
+	if err == nil {
+		err = os.Rename(remote, remote+".away")
+	}
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, err = ds.Fetch(remote, to)
+	if err == nil {
+		t.Errorf("expected an error for an unreachable remote, got no error")
+	}
+	expectFiles(t, to, map[string]bool{"posts/second/post.md": true, ".git/marker": true})
+	err = os.Rename(remote+".away", remote)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, err = newGitDataSource(Options{Ref: "missing"}).Fetch(remote, to)
+	if err == nil {
+		t.Errorf("expected an error for an unknown ref, got no error")
+	}
+	expectFiles(t, to, map[string]bool{"posts/second/post.md": true, ".git/marker": true})
+}
+
+func TestGitFetchRef(t *testing.T) {
+	root, err := ioutil.TempDir("", "blog-gen-git")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+	remote, work := gitRemote(t, root, "posts")
+	first := runGit(t, work, "rev-parse", "HEAD")
+	runGit(t, work, "tag", "v1")
+	commitFile(t, work, "posts/second/post.md", "second")
+	runGit(t, work, "checkout", "-q", "-b", "draft")
+	commitFile(t, work, "posts/draft/post.md", "draft")
+	runGit(t, work, "push", "-q", "origin", "main", "draft", "v1")
+	// a commit that is only reachable from a ref outside of refs/heads and refs/tags
+	runGit(t, work, "checkout", "-q", "main")
+	unadvertised := commitFile(t, work, "posts/review/post.md", "review")
+	runGit(t, work, "push", "-q", "origin", "HEAD:refs/pull/1/head")
+
+	tests := []struct {
+		ref   string
+		err   bool
+		posts []string
+	}{
+		{"", false, []string{"first", "second"}},
+		{"main", false, []string{"first", "second"}},
+		{"draft", false, []string{"draft", "first", "second"}},
+		{"v1", false, []string{"first"}},
+		{first, false, []string{"first"}},
+		{unadvertised, false, []string{"first", "review", "second"}},
+		{"missing", true, nil},
+	}
+
+	for _, tt := range tests {
+		to := filepath.Join(root, "tmp")
+		ds := newGitDataSource(Options{Ref: tt.ref})
+		_, err := ds.Fetch(remote, to)
+		if err != nil && !tt.err {
+			t.Errorf("%s: expected no error, got %v", tt.ref, err)
+		}
+		if err == nil && tt.err {
+			t.Errorf("%s: expected error, got no error", tt.ref)
+		}
+		if tt.err {
+			continue
+		}
+		posts, _ := ioutil.ReadDir(filepath.Join(to, "posts"))
+		var names []string
+		for _, p := range posts {
+			names = append(names, p.Name())
+		}
+		if strings.Join(names, " ") != strings.Join(tt.posts, " ") {
+			t.Errorf("%s: expected posts %v, got %v", tt.ref, tt.posts, names)
+		}
+	}
+}
+
+func TestGitFetchSubdirectory(t *testing.T) {
+	root, err := ioutil.TempDir("", "blog-gen-git")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+	remote, _ := gitRemote(t, root, "posts")
+	to := filepath.Join(root, "tmp")
+
+	ds := newGitDataSource(Options{Subdirectory: "posts"})
+	dirs, err := ds.Fetch(remote, to)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := filepath.Join(to, "first")
+	if len(dirs) != 1 || dirs[0] != expected {
+		t.Errorf("expected posts [%s], got %v", expected, dirs)
+	}
+	expectFiles(t, to, map[string]bool{"first/post.md": true, "README.md": false, ".git": false})
+	expectFiles(t, CheckoutFolder(to, "posts"), map[string]bool{"README.md": true, ".git": true})
+
+	tests := []string{"missing", "README.md", "../posts"}
+	for _, subdirectory := range tests {
+		ds := newGitDataSource(Options{Subdirectory: subdirectory})
+		_, err := ds.Fetch(remote, to)
+		if err == nil {
+			t.Errorf("%s: expected error, got no error", subdirectory)
+		}
+	}
+}
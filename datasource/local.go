@@ -1,22 +1,29 @@
 package datasource
 
 import (
-	"fmt"
+	"path/filepath"
 
 	"github.com/RomanosTrechlis/blog-gen/util/fs"
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
 )
 
 // localDataSource is the local data source object
-type localDataSource struct{}
+type localDataSource struct {
+	logged
+	subdirectory string
+}
 
 // newLocalDataSource creates a new LocalDataSource
-func newLocalDataSource() (ds DataSource) {
-	return &localDataSource{}
+func newLocalDataSource(opts Options) (ds DataSource) {
+	return &localDataSource{subdirectory: opts.Subdirectory}
 }
 
 // Fetch creates the output folder, clears it and copies the local folder there
 func (ds *localDataSource) Fetch(from, to string) (dirs []string, err error) {
-	fmt.Printf("Fetching data from %s into %s...\n", from, to)
+	if ds.subdirectory != "" {
+		from = filepath.Join(from, filepath.FromSlash(ds.subdirectory))
+	}
+	logger.Infof(ds.log, "Fetching data from %s into %s...", from, to)
 	err = fs.CreateFolderIfNotExist(to)
 	if err != nil {
 		return nil, err
@@ -29,10 +36,10 @@ func (ds *localDataSource) Fetch(from, to string) (dirs []string, err error) {
 	if err != nil {
 		return nil, err
 	}
-	dirs, err = fs.GetContentFolders(to)
+	dirs, err = fs.GetContent(to)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Print("Fetching complete.\n")
+	logger.Debugf(ds.log, "Fetching complete.")
 	return dirs, nil
 }
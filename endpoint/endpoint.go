@@ -2,18 +2,96 @@ package endpoint
 
 import (
 	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
 )
 
+// Endpoint uploads the generated blog
 type Endpoint interface {
-	Upload(destFolder, endpointUsername, endpointPassword, endpointURL string) error
+	Upload(destFolder string, target Target) error
+}
+
+// Target holds the information needed to upload to an endpoint
+type Target struct {
+	URL      string
+	Username string
+	Password string
+	// Branch is the branch of the endpoint that holds the blog
+	Branch string
+	// Message is the template of the commit message. It can use
+	// {{.Commit}} for the commit of the source and {{.BuildTime}}
+	// for the time the blog was generated.
+	Message string
+	// Source is the folder the blog was generated from
+	Source string
+}
+
+// Factory creates an endpoint from the options of the configuration
+// file. The options hold the type specific settings of the endpoint.
+type Factory func(options map[string]interface{}) (Endpoint, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+func init() {
+	Register("git", func(options map[string]interface{}) (Endpoint, error) {
+		return newGitEndpoint(), nil
+	})
+}
+
+// Register makes an endpoint type available to New. It panics if
+// name is empty, factory is nil or name is already registered.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if name == "" || factory == nil {
+		panic("endpoint: Register needs a name and a factory")
+	}
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("endpoint: Register called twice for type '%s'", name))
+	}
+	factories[name] = factory
+}
+
+// Types returns the sorted names of the registered endpoint types
+func Types() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	types := make([]string, 0, len(factories))
+	for name := range factories {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// New creates an endpoint of the registered endpointType with the given options.
+func New(endpointType string, options map[string]interface{}) (endpoint Endpoint, err error) {
+	if endpointType == "" {
+		return nil, fmt.Errorf("no endpoint information found in the config file")
+	}
+	factoriesMu.RLock()
+	factory, ok := factories[endpointType]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown endpoint type '%s'", endpointType)
+	}
+	endpoint, err = factory(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s endpoint: %v", endpointType, err)
+	}
+	return endpoint, nil
 }
 
-func New(endpointType string) (endpoint Endpoint, err error) {
-	switch endpointType {
-	case "git":
-		endpoint = newGitEndpoint()
-	default:
-		err = fmt.Errorf("no endpoint information found in the config file")
+// SetLogger sends the messages of e to log. The endpoints that log their
+// progress have a SetLogger(logger.Logger) method, the rest are left as
+// they are.
+func SetLogger(e Endpoint, log logger.Logger) {
+	if l, ok := e.(interface{ SetLogger(logger.Logger) }); ok {
+		l.SetLogger(log)
 	}
-	return endpoint, err
 }
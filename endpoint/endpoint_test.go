@@ -0,0 +1,55 @@
+package endpoint
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeEndpoint is an endpoint registered by the tests
+type fakeEndpoint struct {
+	bucket interface{}
+}
+
+func (e *fakeEndpoint) Upload(destFolder string, target Target) error {
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	Register("fake", func(options map[string]interface{}) (Endpoint, error) {
+		return &fakeEndpoint{bucket: options["Bucket"]}, nil
+	})
+
+	tests := []struct {
+		endpointType string
+		err          bool
+	}{
+		{"", true},
+		{"unknown", true},
+		{"git", false},
+		{"fake", false},
+	}
+	for _, tt := range tests {
+		_, err := New(tt.endpointType, map[string]interface{}{"Bucket": "blog"})
+		if err != nil && !tt.err {
+			t.Errorf("%s: expected no error, got %v", tt.endpointType, err)
+		}
+		if err == nil && tt.err {
+			t.Errorf("%s: expected error, got no error", tt.endpointType)
+		}
+	}
+
+	e, _ := New("fake", map[string]interface{}{"Bucket": "blog"})
+	if fake, ok := e.(*fakeEndpoint); !ok || fake.bucket != "blog" {
+		t.Errorf("expected the registered endpoint with its options, got %+v", e)
+	}
+}
+
+func TestHideCred(t *testing.T) {
+	credURL, _ := createUrlWithCred("user", "p@ss", "https://github.com/user/repo.git")
+	err := fmt.Errorf("fatal: unable to access '%s': p@ss rejected", credURL)
+	msg := hideCred(err, credURL, "https://github.com/user/repo.git").Error()
+	if strings.Contains(msg, "p@ss") || strings.Contains(msg, "p%40ss") {
+		t.Errorf("expected no password, got '%s'", msg)
+	}
+}
@@ -1,56 +1,78 @@
 package endpoint
 
 import (
+	"bytes"
 	"fmt"
+	neturl "net/url"
+	"os"
 	"os/exec"
-
+	"path/filepath"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/RomanosTrechlis/blog-gen/util/fs"
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
+)
+
+const (
+	defaultBranch   = "master"
+	defaultMessage  = "Update site from {{.Commit}} built at {{.BuildTime}}"
+	buildTimeFormat = "2006-01-02 15:04:05 -0700"
 )
 
 // gitEndpoint is the git endpoint object
-type gitEndpoint struct{}
+type gitEndpoint struct {
+	log logger.Logger
+}
+
+// SetLogger sends the messages of the endpoint to log
+func (ds *gitEndpoint) SetLogger(log logger.Logger) {
+	ds.log = log
+}
 
 // newGitEndpoint creates a new GitEndpoint
 func newGitEndpoint() (e Endpoint) {
 	return &gitEndpoint{}
 }
 
-// Upload uploads the site to a git repository
-// todo: push fails
-func (ds *gitEndpoint) Upload(destFolder, endpointUsername, endpointPassword, endpointURL string) (err error) {
-	fmt.Println("Uploading Site...")
-	dest := destFolder + "_upload"
-	err = fs.CreateFolderIfNotExist(dest)
+// commitInfo is the data available to the commit message template
+type commitInfo struct {
+	Commit    string
+	BuildTime string
+}
+
+// Upload uploads the site to a branch of a git repository. The upload
+// folder keeps the history of the branch, so only the real differences
+// of the generated blog are committed.
+func (ds *gitEndpoint) Upload(destFolder string, target Target) (err error) {
+	logger.Infof(ds.log, "Uploading Site...")
+	branch := target.Branch
+	if branch == "" {
+		branch = defaultBranch
+	}
+	message, err := commitMessage(destFolder, target)
 	if err != nil {
 		return err
 	}
-	err = fs.ClearFolder(dest)
+	url, err := createUrlWithCred(target.Username, target.Password, target.URL)
 	if err != nil {
 		return err
 	}
 
-	cmdName := "git"
-	initArgs := []string{"init", "."}
-	cmd := exec.Command(cmdName, initArgs...)
-	cmd.Dir = dest
-	err = cmd.Run()
+	dest := destFolder + "_upload"
+	err = fs.CreateFolderIfNotExist(dest)
 	if err != nil {
-		return fmt.Errorf("error initializing git repository at %s: %v", destFolder, err)
+		return err
 	}
-
-	url, err := createUrlWithCred(endpointUsername, endpointPassword, endpointURL)
+	err = checkoutBranch(dest, url, branch)
 	if err != nil {
-		return fmt.Errorf("%v", err)
+		return fmt.Errorf("error checking out branch %s of %s: %v", branch, target.URL, err)
 	}
-	remoteArgs := []string{"remote", "add", "origin", url}
-	cmd = exec.Command(cmdName, remoteArgs...)
-	cmd.Dir = dest
-	err = cmd.Run()
 
+	err = clearWorkTree(dest)
 	if err != nil {
-		return fmt.Errorf("error creating upload folder %s: %v", dest, err)
+		return fmt.Errorf("error clearing upload folder %s: %v", dest, err)
 	}
 	err = fs.CopyDir(destFolder, dest)
 	if err != nil {
@@ -58,38 +80,173 @@ func (ds *gitEndpoint) Upload(destFolder, endpointUsername, endpointPassword, en
 			destFolder, dest, err)
 	}
 
-	addArgs := []string{"add", "."}
-	cmd = exec.Command(cmdName, addArgs...)
-	cmd.Dir = dest
-	err = cmd.Run()
+	_, err = git(dest, "add", "--all", ".")
 	if err != nil {
 		return fmt.Errorf("error adding files to commit: %v", err)
 	}
+	status, err := git(dest, "status", "--porcelain")
+	if err != nil {
+		return fmt.Errorf("error reading status of %s: %v", dest, err)
+	}
+	if status == "" {
+		logger.Infof(ds.log, "Nothing to upload, the site is up to date.")
+		return nil
+	}
 
-	commitArgs := []string{"commit", "-m", "auto commit"}
-	cmd = exec.Command(cmdName, commitArgs...)
-	cmd.Dir = dest
-	err = cmd.Run()
+	_, err = git(dest, "commit", "-m", message)
 	if err != nil {
 		return fmt.Errorf("error committing files: %v", err)
 	}
+	_, err = git(dest, "push", url, "HEAD:refs/heads/"+branch)
+	if err != nil {
+		return fmt.Errorf("error pushing to remote %s: %v", target.URL, hideCred(err, url, target.URL))
+	}
+	logger.Infof(ds.log, "Upload Complete.")
+	return nil
+}
+
+// checkoutBranch prepares path to hold the given branch of the repository.
+// If the branch exists on the remote it is fetched and checked out,
+// otherwise a new repository with an empty branch is created.
+func checkoutBranch(path, url, branch string) (err error) {
+	// the full ref, since a short name also matches e.g. refs/heads/x/<branch>
+	ref := "refs/heads/" + branch
+	heads, err := git(path, "ls-remote", "--heads", url, ref)
+	if err != nil {
+		return fmt.Errorf("error reading remote branches: %v", hideCred(err, url, ""))
+	}
+
+	if heads == "" {
+		err = fs.ClearFolder(path)
+		if err != nil {
+			return err
+		}
+		_, err = git(path, "init", ".")
+		if err != nil {
+			return err
+		}
+		_, err = git(path, "symbolic-ref", "HEAD", ref)
+		return err
+	}
+
+	_, err = os.Stat(filepath.Join(path, ".git"))
+	if err != nil {
+		err = fs.ClearFolder(path)
+		if err != nil {
+			return err
+		}
+		_, err = git(path, "init", ".")
+		if err != nil {
+			return err
+		}
+	}
+	_, err = git(path, "fetch", url, ref)
+	if err != nil {
+		return hideCred(err, url, "")
+	}
+	_, err = git(path, "checkout", "--force", "-B", branch, "FETCH_HEAD")
+	return err
+}
 
-	pushArgs := []string{"push", "origin", "master"}
-	cmd = exec.Command(cmdName, pushArgs...)
-	cmd.Dir = dest
-	err = cmd.Run()
+// clearWorkTree removes everything from path except the git folder
+func clearWorkTree(path string) (err error) {
+	dir, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("error pushing to remote %s: %v", endpointURL, err)
+		return err
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == ".git" {
+			continue
+		}
+		err = os.RemoveAll(filepath.Join(path, name))
+		if err != nil {
+			return err
+		}
 	}
-	fmt.Println("Upload Complete.")
 	return nil
 }
 
+// commitMessage executes the message template of the target
+func commitMessage(destFolder string, target Target) (message string, err error) {
+	text := target.Message
+	if text == "" {
+		text = defaultMessage
+	}
+	tmpl, err := template.New("message").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("error parsing commit message template %s: %v", text, err)
+	}
+
+	info := commitInfo{Commit: "unknown", BuildTime: time.Now().Format(buildTimeFormat)}
+	if target.Source != "" {
+		commit, err := git(target.Source, "rev-parse", "--short", "HEAD")
+		if err == nil && commit != "" {
+			info.Commit = commit
+		}
+	}
+	stat, err := os.Stat(filepath.Join(destFolder, "index.html"))
+	if err == nil {
+		info.BuildTime = stat.ModTime().Format(buildTimeFormat)
+	}
+
+	buf := bytes.Buffer{}
+	err = tmpl.Execute(&buf, info)
+	if err != nil {
+		return "", fmt.Errorf("error executing commit message template %s: %v", text, err)
+	}
+	return buf.String(), nil
+}
+
+// git runs a git command inside dir and returns its trimmed output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return "", err
+		}
+		return "", fmt.Errorf("%v: %s", err, msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// hideCred replaces the url with credentials and the password inside err
+func hideCred(err error, credURL, url string) error {
+	if err == nil {
+		return nil
+	}
+	if url == "" {
+		url = "<remote>"
+	}
+	msg := strings.Replace(err.Error(), credURL, url, -1)
+	// git can also print the password on its own, e.g. decoded
+	if u, e := neturl.Parse(credURL); e == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok && password != "" {
+			msg = strings.Replace(msg, password, "xxxxx", -1)
+			msg = strings.Replace(msg, neturl.QueryEscape(password), "xxxxx", -1)
+		}
+	}
+	return fmt.Errorf("%s", msg)
+}
+
 func createUrlWithCred(username, password, to string) (url string, err error) {
 	t := strings.Split(to, "://")
 	if len(t) != 2 {
 		return "", fmt.Errorf("couldn't process git url")
 	}
+	if username == "" && password == "" {
+		return to, nil
+	}
 	p := strings.Replace(password, "@", "%40", 5)
 	return t[0] + "://" + username + ":" + p + "@" + t[1], nil
 }
@@ -0,0 +1,129 @@
+package endpoint
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runGit runs a git command in dir for the tests
+func runGit(t *testing.T, dir string, args ...string) string {
+	out, err := git(dir, args...)
+	if err != nil {
+		t.Fatalf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return out
+}
+
+// gitIdentity sets the author of the test commits
+func gitIdentity() func() {
+	vars := map[string]string{
+		"GIT_AUTHOR_NAME":     "Tester",
+		"GIT_AUTHOR_EMAIL":    "tester@example.com",
+		"GIT_COMMITTER_NAME":  "Tester",
+		"GIT_COMMITTER_EMAIL": "tester@example.com",
+	}
+	old := make(map[string]string)
+	for k, v := range vars {
+		old[k] = os.Getenv(k)
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k, v := range old {
+			os.Setenv(k, v)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err == nil {
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+	}
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestGitUpload(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	defer gitIdentity()()
+	root, err := ioutil.TempDir("", "blog-gen-upload")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	remote := filepath.Join(root, "remote.git")
+	os.MkdirAll(remote, os.ModePerm)
+	runGit(t, remote, "init", "--bare", ".")
+	url := "file://" + filepath.ToSlash(remote)
+
+	// the source of the blog, whose commit is in the commit message
+	source := filepath.Join(root, "source")
+	os.MkdirAll(source, os.ModePerm)
+	runGit(t, source, "init", ".")
+	runGit(t, source, "commit", "--allow-empty", "-m", "posts")
+	commit := runGit(t, source, "rev-parse", "--short", "HEAD")
+	// a branch whose name ends with the one of the blog
+	runGit(t, source, "push", url, "HEAD:refs/heads/site/pages")
+
+	dest := filepath.Join(root, "public")
+	target := Target{URL: url, Branch: "pages", Message: "Build {{.Commit}}", Source: source}
+	e := newGitEndpoint()
+	upload := func(content string) {
+		writeFile(t, filepath.Join(dest, "index.html"), content)
+		err := e.Upload(dest, target)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	commits := func() string {
+		return runGit(t, remote, "rev-list", "--count", "refs/heads/pages")
+	}
+
+	upload("1")
+	if commits() != "1" {
+		t.Fatalf("expected 1 commit, got %s", commits())
+	}
+	upload("1")
+	if commits() != "1" {
+		t.Errorf("expected no commit without changes, got %s commits", commits())
+	}
+	upload("2")
+	if commits() != "2" {
+		t.Errorf("expected 2 commits, got %s", commits())
+	}
+	// a new upload folder fetches the history of the branch
+	os.RemoveAll(dest + "_upload")
+	upload("3")
+	if commits() != "3" {
+		t.Errorf("expected 3 commits, got %s", commits())
+	}
+
+	if msg := runGit(t, remote, "log", "-1", "--format=%s", "refs/heads/pages"); msg != "Build "+commit {
+		t.Errorf("expected message 'Build %s', got '%s'", commit, msg)
+	}
+	if content := runGit(t, remote, "show", "refs/heads/pages:index.html"); content != "3" {
+		t.Errorf("expected the last build in the branch, got '%s'", content)
+	}
+	if n := runGit(t, remote, "rev-list", "--count", "refs/heads/site/pages"); n != "1" {
+		t.Errorf("expected the other branch to be left alone, got %s commits", n)
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	msg, err := commitMessage("", Target{Message: "Site at {{.Commit}}"})
+	if err != nil || msg != "Site at unknown" {
+		t.Errorf("expected the message of the template, got '%s', %v", msg, err)
+	}
+	_, err = commitMessage("", Target{Message: "{{.Missing"})
+	if err == nil {
+		t.Errorf("expected an error for a broken template")
+	}
+}
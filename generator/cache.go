@@ -0,0 +1,335 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RomanosTrechlis/blog-gen/config"
+)
+
+// cacheVersion changes when the same inputs give different pages, so
+// that the caches of older versions are not used
+const cacheVersion = "1"
+
+// buildCache records the hashes of the inputs of the posts and the pages
+// of a build, so that the next build reuses the pages that did not change
+// instead of generating them again.
+type buildCache struct {
+	// Site is the hash of the inputs that every page depends on, like the
+	// theme and the configuration. Nothing is reused when it changes.
+	Site string
+	// Posts holds the rendered posts by the path of their source
+	Posts map[string]*cachedPost
+	// Pages holds the hashes of the inputs of the tasks by their name
+	Pages map[string]string
+
+	mu       sync.Mutex
+	previous *buildCache
+	// from is the folder of the previous build and to the folder of
+	// the new one
+	from, to string
+	reused   int
+	pages    []Page
+}
+
+// cachedPost is a post as it was read from its source
+type cachedPost struct {
+	Hash string
+	Meta *Meta
+	HTML string
+}
+
+// cacheFile returns the file that keeps the cache of the build of dest
+func cacheFile(dest string) string {
+	return siblingFolder(dest, "cache.json")
+}
+
+// loadCache returns the cache of the build from the folder of the previous
+// build to the folder to. The previous cache is used only when its site
+// hash is site and force is false.
+func loadCache(from, to, site string, force bool) *buildCache {
+	c := &buildCache{
+		Site:  site,
+		Posts: make(map[string]*cachedPost),
+		Pages: make(map[string]string),
+		from:  from,
+		to:    to,
+	}
+	if force {
+		return c
+	}
+	b, err := ioutil.ReadFile(cacheFile(from))
+	if err != nil {
+		return c
+	}
+	previous := &buildCache{}
+	err = json.Unmarshal(b, previous)
+	if err != nil || previous.Site != site {
+		return c
+	}
+	c.previous = previous
+	return c
+}
+
+// save writes the cache next to the folder of the build dest
+func (c *buildCache) save(dest string) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error creating build cache: %v", err)
+	}
+	file := cacheFile(dest)
+	err = ioutil.WriteFile(file, b, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing file %s: %v", file, err)
+	}
+	return nil
+}
+
+// cachedPost returns the post of the previous build that was read from
+// path, if its files still have the same hash
+func (c *buildCache) cachedPost(path, hash string) *cachedPost {
+	if c == nil || c.previous == nil {
+		return nil
+	}
+	p, ok := c.previous.Posts[path]
+	if !ok || p.Hash != hash || p.Meta == nil {
+		return nil
+	}
+	return p
+}
+
+// storePost records the post that was read from path
+func (c *buildCache) storePost(path string, p *cachedPost) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Posts[path] = p
+}
+
+// reuse copies the outputs of t from the previous build, when its inputs
+// did not change. It reports whether t can be skipped.
+func (c *buildCache) reuse(t task) bool {
+	if c == nil || c.previous == nil || t.hash == "" || c.previous.Pages[t.name] != t.hash {
+		return false
+	}
+	for i, output := range t.outputs {
+		err := linkTree(filepath.Join(c.from, output), filepath.Join(c.to, output))
+		if err != nil {
+			// the partial copies are removed, since the task writes them again
+			for _, o := range t.outputs[:i+1] {
+				os.RemoveAll(filepath.Join(c.to, o))
+			}
+			return false
+		}
+	}
+	c.store(t)
+	c.mu.Lock()
+	c.reused++
+	if t.page.URL != "" {
+		page := t.page
+		page.Reused = true
+		c.pages = append(c.pages, page)
+	}
+	c.mu.Unlock()
+	return true
+}
+
+// store records the hash of t after it is generated
+func (c *buildCache) store(t task) {
+	if c == nil || t.hash == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Pages[t.name] = t.hash
+}
+
+// siteHash returns the hash of the configuration, the theme and the
+// other inputs that every page of the site depends on
+func siteHash(siteInfo *config.SiteInformation) (string, error) {
+	b, err := json.Marshal(siteInfo)
+	if err != nil {
+		return "", fmt.Errorf("error hashing configuration: %v", err)
+	}
+	theme, err := hashFiles(siteInfo.ThemeFolder)
+	if err != nil {
+		return "", err
+	}
+	// the pages show the current year
+	year := strconv.Itoa(time.Now().Year())
+	return hashOf(cacheVersion, string(b), theme, year), nil
+}
+
+// hashOf returns the hash of parts
+func hashOf(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		io.WriteString(h, p)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashFiles returns the hash of the names and the contents of the files
+// in path, or of the file path
+func hashFiles(path string) (string, error) {
+	h := sha256.New()
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		io.WriteString(h, filepath.ToSlash(rel))
+		h.Write([]byte{0})
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("error hashing %s: %v", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// linkFile creates a hard link. The tests replace it to copy the files.
+var linkFile = os.Link
+
+// linkTree creates the files of src in dst as hard links, or copies them
+// when they cannot be linked, e.g. across devices. The pages are always written to new files,
+// so the builds never change each other's files.
+func linkTree(src, dst string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		err = os.MkdirAll(filepath.Dir(target), os.ModePerm)
+		if err != nil {
+			return err
+		}
+		if linkFile(p, target) == nil {
+			return nil
+		}
+		return copyFile(p, target)
+	})
+}
+
+// copyFile copies the file src to the file dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("error reading file %s: %v", src, err)
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("error creating file %s: %v", dst, err)
+	}
+	_, err = io.Copy(out, in)
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return fmt.Errorf("error copying file %s to %s: %v", src, dst, err)
+	}
+	return nil
+}
+
+// postOutputs returns the file of the post of link and the folder of its
+// images and artifacts, relative to the destination folder. Posts that
+// are not in a folder of their own have that folder only with assets.
+func postOutputs(link string, assets bool) []string {
+	folder := filepath.FromSlash(strings.Trim(assetLink(link), "/"))
+	if isFolderLink(link) {
+		return []string{folder}
+	}
+	if !assets {
+		return []string{pageOutput(link)}
+	}
+	return []string{pageOutput(link), folder}
+}
+
+// pageOutput returns the file of the page of link, relative to the
+// destination folder
+func pageOutput(link string) string {
+	return filepath.FromSlash(pageFile(link))
+}
+
+// listingTask returns the task of a listing page, whose inputs are its
+// posts and its place among the other pages
+func listingTask(lg *listingGenerator) task {
+	parts := []string{lg.link, lg.pageTitle, strconv.Itoa(lg.pageNum), strconv.Itoa(lg.maxPageNum), postsHash(lg.posts)}
+	return task{
+		name:      "page " + lg.link,
+		generator: lg,
+		hash:      hashOf(parts...),
+		outputs:   []string{pageOutput(lg.link)},
+		page:      Page{URL: lg.link, Title: lg.pageTitle},
+	}
+}
+
+// indexTask returns the task of the index page of the tags or the
+// categories, whose inputs are their names and their number of posts
+func indexTask(link, title string, g Generator, postsMap map[string][]*post) task {
+	return task{
+		name:      "page " + link,
+		generator: g,
+		hash:      hashOf(link, countsHash(postsMap)),
+		outputs:   []string{pageOutput(link)},
+		page:      Page{URL: link, Title: title},
+	}
+}
+
+// postsHash returns the hash of the files and the order of posts
+func postsHash(posts []*post) string {
+	parts := make([]string, 0, len(posts))
+	for _, p := range posts {
+		parts = append(parts, p.hash)
+	}
+	return hashOf(parts...)
+}
+
+// countsHash returns the hash of the names of postsMap and their number
+// of posts
+func countsHash(postsMap map[string][]*post) string {
+	parts := make([]string, 0, len(postsMap))
+	for name, posts := range postsMap {
+		parts = append(parts, fmt.Sprintf("%s:%d", name, len(posts)))
+	}
+	sort.Strings(parts)
+	return hashOf(parts...)
+}
+
+func sitemapHash(posts []*post, tagPostsMap, catPostsMap map[string][]*post) string {
+	return hashOf(postsHash(posts), countsHash(tagPostsMap), countsHash(catPostsMap))
+}
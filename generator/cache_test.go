@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildCacheReuse(t *testing.T) {
+	root, err := ioutil.TempDir("", "blog-gen-cache")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+	from := filepath.Join(root, "public")
+	to := stagingFolder(from)
+	writeFile(t, filepath.Join(from, "my-post", "index.html"), "post")
+	writeFile(t, filepath.Join(from, "my-post", "images", "a.png"), "image")
+
+	previous := loadCache(from, to, "site", false)
+	previous.Pages["post"] = "1"
+	err = previous.save(from)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	post := task{name: "post", hash: "1", outputs: postOutputs("/my-post/", true)}
+	c := loadCache(from, to, "site", false)
+	if !c.reuse(post) {
+		t.Fatalf("expected unchanged post to be reused")
+	}
+	expectContent(t, filepath.Join(to, "my-post", "index.html"), "post")
+	expectContent(t, filepath.Join(to, "my-post", "images", "a.png"), "image")
+	if c.Pages["post"] != "1" {
+		t.Errorf("expected reused post in the new cache")
+	}
+
+	changed := task{name: "post", hash: "2", outputs: post.outputs}
+	if c.reuse(changed) {
+		t.Errorf("expected changed post not to be reused")
+	}
+	missing := task{name: "post", hash: "1", outputs: []string{pageOutput("/other/")}}
+	if c.reuse(missing) {
+		t.Errorf("expected post without previous output not to be reused")
+	}
+	if loadCache(from, to, "other site", false).reuse(post) {
+		t.Errorf("expected nothing to be reused when the site changes")
+	}
+	if loadCache(from, to, "site", true).reuse(post) {
+		t.Errorf("expected nothing to be reused when forced")
+	}
+}
+
+func TestHashFiles(t *testing.T) {
+	root, err := ioutil.TempDir("", "blog-gen-hash")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+	writeFile(t, filepath.Join(root, "post.md"), "# hello")
+
+	first, err := hashFiles(root)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	writeFile(t, filepath.Join(root, "images", "a.png"), "image")
+	second, err := hashFiles(root)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if first == second {
+		t.Errorf("expected a new image to change the hash")
+	}
+}
+
+func TestLinkTreeCopy(t *testing.T) {
+	root, err := ioutil.TempDir("", "blog-gen-link")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	writeFile(t, filepath.Join(src, "CNAME"), "example.com")
+	writeFile(t, filepath.Join(src, "post", "_redirects"), "/old /new")
+
+	// files cannot be linked, e.g. across devices, so they are copied
+	defer func(link func(string, string) error) { linkFile = link }(linkFile)
+	linkFile = func(oldname, newname string) error {
+		return fmt.Errorf("invalid cross-device link")
+	}
+	err = linkTree(src, dst)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectContent(t, filepath.Join(dst, "CNAME"), "example.com")
+	expectContent(t, filepath.Join(dst, "post", "_redirects"), "/old /new")
+}
@@ -6,9 +6,9 @@ import (
 	"html/template"
 	"path/filepath"
 	"sort"
-	"strings"
 
 	"github.com/RomanosTrechlis/blog-gen/config"
+	"github.com/RomanosTrechlis/blog-gen/util/url"
 )
 
 // Category holds the data for a category
@@ -22,44 +22,32 @@ type Category struct {
 type categoriesGenerator struct {
 	catPostsMap map[string][]*post
 	template    *template.Template
-	destination string
 	siteInfo    *config.SiteInformation
+	urls        *url.Builder
+	out         *output
 }
 
-// Generate creates the categories page
+// Generate creates the categories page. The page of every category is
+// a listing.
 func (g *categoriesGenerator) Generate() (err error) {
-	fmt.Println("\tGenerating Categories...")
-	catPostsMap := g.catPostsMap
-	destination := g.destination
-	catsPath := filepath.Join(destination, "categories")
-	err = clearAndCreateDestination(catsPath)
-	if err != nil {
-		return err
-	}
+	g.out.debugf("Generating Categories...")
 	err = g.generateCatIndex()
 	if err != nil {
 		return err
 	}
-	for cat, catPosts := range catPostsMap {
-		catPagePath := filepath.Join(catsPath, cat)
-		err = g.generateCatPage(cat, catPosts, catPagePath)
-		if err != nil {
-			return err
-		}
-	}
-	fmt.Println("\tFinished generating Categories...")
+	g.out.debugf("Finished generating Categories...")
 	return nil
 }
 
 func (g *categoriesGenerator) generateCatIndex() (err error) {
 	catTemplatePath := filepath.Join(g.siteInfo.ThemeFolder, "categories.html")
-	tmpl, err := getTemplate(catTemplatePath)
+	tmpl, err := getTemplate(catTemplatePath, g.urls)
 	if err != nil {
 		return err
 	}
 	categories := []*Category{}
 	for cat, posts := range g.catPostsMap {
-		categories = append(categories, &Category{Name: cat, Link: getCatLink(cat), Count: len(posts)})
+		categories = append(categories, &Category{Name: cat, Link: g.urls.RelURL(getCatLink(g.siteInfo, cat)), Count: len(posts)})
 	}
 	sort.Sort(categoryByCountDesc(categories))
 	buf := bytes.Buffer{}
@@ -69,7 +57,7 @@ func (g *categoriesGenerator) generateCatIndex() (err error) {
 	}
 
 	c := htmlConfig{
-		path:       fmt.Sprintf("%s/categories", g.destination),
+		link:       getCatIndexLink(g.siteInfo),
 		pageTitle:  "Categories",
 		pageNum:    0,
 		maxPageNum: 0,
@@ -77,6 +65,8 @@ func (g *categoriesGenerator) generateCatIndex() (err error) {
 		temp:       g.template,
 		content:    template.HTML(buf.String()),
 		siteInfo:   g.siteInfo,
+		urls:       g.urls,
+		out:        g.out,
 	}
 	err = c.writeHTML()
 	if err != nil {
@@ -85,30 +75,6 @@ func (g *categoriesGenerator) generateCatIndex() (err error) {
 	return nil
 }
 
-func (g *categoriesGenerator) generateCatPage(cat string, posts []*post, path string) (err error) {
-	err = clearAndCreateDestination(path)
-	if err != nil {
-		return err
-	}
-	lg := listingGenerator{
-		posts:       posts,
-		template:    g.template,
-		destination: path,
-		pageTitle:   cat,
-		siteInfo:    g.siteInfo,
-	}
-	err = lg.Generate()
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func getCatLink(cat string) (link string) {
-	link = fmt.Sprintf("/categories/%s/", strings.ToLower(cat))
-	return link
-}
-
 // categoryByCountDesc sorts the cats
 type categoryByCountDesc []*Category
 
@@ -1,2 +1,11 @@
-// Package generator contains implementations of generator interface
+// Package generator contains implementations of generator interface.
+//
+// New creates a SiteGenerator from functional options and Build generates
+// the blog, returning a Result with its pages:
+//
+//	g, err := generator.New(generator.WithConfig(siteInfo), generator.WithSources(sources...))
+//	if err != nil {
+//		return err
+//	}
+//	result, err := g.Build(ctx)
 package generator
@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	yamlDelimiter = "---"
+	tomlDelimiter = "+++"
+)
+
+// splitFrontMatter separates the front matter at the top of a post from its
+// markdown. The front matter is enclosed in "---" lines for YAML or in "+++"
+// lines for TOML. When there is no front matter, frontMatter is nil and body
+// is the whole input.
+func splitFrontMatter(input []byte) (delimiter string, frontMatter, body []byte, err error) {
+	input = bytes.TrimPrefix(input, []byte("\xef\xbb\xbf"))
+	first, rest := cutLine(input)
+	delimiter = strings.TrimSpace(string(first))
+	if delimiter != yamlDelimiter && delimiter != tomlDelimiter {
+		return "", nil, input, nil
+	}
+
+	offset := len(input) - len(rest)
+	for len(rest) > 0 {
+		line, next := cutLine(rest)
+		if strings.TrimSpace(string(line)) == delimiter {
+			end := len(input) - len(rest)
+			return delimiter, input[offset:end], next, nil
+		}
+		rest = next
+	}
+	return "", nil, nil, fmt.Errorf("front matter starting with '%s' is not closed", delimiter)
+}
+
+// cutLine returns the first line of b without the line ending, and the rest of b
+func cutLine(b []byte) (line, rest []byte) {
+	i := bytes.IndexByte(b, '\n')
+	if i == -1 {
+		return b, nil
+	}
+	return bytes.TrimSuffix(b[:i], []byte("\r")), b[i+1:]
+}
+
+// parseFrontMatter parses the front matter into meta
+func parseFrontMatter(delimiter string, frontMatter []byte, meta *Meta) (err error) {
+	switch delimiter {
+	case yamlDelimiter:
+		err = yaml.Unmarshal(frontMatter, meta)
+		if err != nil {
+			return fmt.Errorf("error reading yaml front matter: %v", err)
+		}
+	case tomlDelimiter:
+		_, err = toml.Decode(string(frontMatter), meta)
+		if err != nil {
+			return fmt.Errorf("error reading toml front matter: %v", err)
+		}
+	}
+	return nil
+}
+
+// mergeMeta fills the empty fields of meta with the fields of other. It fails
+// when a field is set in both with different values.
+func mergeMeta(meta, other *Meta) error {
+	m := reflect.ValueOf(meta).Elem()
+	o := reflect.ValueOf(other).Elem()
+	var conflicts []string
+	for i := 0; i < m.NumField(); i++ {
+		field, otherField := m.Field(i), o.Field(i)
+		if isZero(otherField) {
+			continue
+		}
+		if isZero(field) {
+			field.Set(otherField)
+			continue
+		}
+		if !reflect.DeepEqual(field.Interface(), otherField.Interface()) {
+			conflicts = append(conflicts, m.Type().Field(i).Name)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("meta.yml and front matter define different values for %s",
+			strings.Join(conflicts, ", "))
+	}
+	return nil
+}
+
+func isZero(v reflect.Value) bool {
+	if v.Kind() == reflect.Slice {
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
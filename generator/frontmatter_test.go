@@ -0,0 +1,102 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		desc        string
+		input       string
+		delimiter   string
+		frontMatter string
+		body        string
+		err         bool
+	}{
+		{"no front matter", "# Title\n\ntext", "", "", "# Title\n\ntext", false},
+		{"yaml", "---\ntitle: Post\n---\n# Title\n", "---", "title: Post\n", "# Title\n", false},
+		{"toml", "+++\ntitle = \"Post\"\n+++\ntext", "+++", "title = \"Post\"\n", "text", false},
+		{"windows line endings", "---\r\ntitle: Post\r\n---\r\ntext", "---", "title: Post\r\n", "text", false},
+		{"empty body", "---\ntitle: Post\n---", "---", "title: Post\n", "", false},
+		{"not closed", "---\ntitle: Post\n", "", "", "", true},
+		{"different delimiters", "---\ntitle: Post\n+++\ntext", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		delimiter, frontMatter, body, err := splitFrontMatter([]byte(tt.input))
+		if err != nil && !tt.err {
+			t.Errorf("%s: expected no error, got %v", tt.desc, err)
+		}
+		if err == nil && tt.err {
+			t.Errorf("%s: expected error, got no error", tt.desc)
+		}
+		if err != nil {
+			continue
+		}
+		if delimiter != tt.delimiter {
+			t.Errorf("%s: expected delimiter '%s', got '%s'", tt.desc, tt.delimiter, delimiter)
+		}
+		if string(frontMatter) != tt.frontMatter {
+			t.Errorf("%s: expected front matter '%s', got '%s'", tt.desc, tt.frontMatter, frontMatter)
+		}
+		if string(body) != tt.body {
+			t.Errorf("%s: expected body '%s', got '%s'", tt.desc, tt.body, body)
+		}
+	}
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	expected := Meta{Title: "Post", Date: "2020-01-02 15:04:05", Tags: []string{"go", "web"}, Draft: true}
+	tests := []struct {
+		delimiter   string
+		frontMatter string
+	}{
+		{yamlDelimiter, "title: Post\ndate: 2020-01-02 15:04:05\ntags:\n- go\n- web\ndraft: true\n"},
+		{tomlDelimiter, "title = \"Post\"\ndate = \"2020-01-02 15:04:05\"\ntags = [\"go\", \"web\"]\ndraft = true\n"},
+	}
+
+	for _, tt := range tests {
+		meta := Meta{}
+		err := parseFrontMatter(tt.delimiter, []byte(tt.frontMatter), &meta)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+			continue
+		}
+		if !reflect.DeepEqual(meta, expected) {
+			t.Errorf("expected %+v, got %+v", expected, meta)
+		}
+	}
+}
+
+func TestMergeMeta(t *testing.T) {
+	tests := []struct {
+		desc   string
+		meta   Meta
+		other  Meta
+		result Meta
+		err    bool
+	}{
+		{"fills empty fields", Meta{Title: "Post"}, Meta{Date: "2020", Tags: []string{"go"}},
+			Meta{Title: "Post", Date: "2020", Tags: []string{"go"}}, false},
+		{"same values", Meta{Title: "Post"}, Meta{Title: "Post"}, Meta{Title: "Post"}, false},
+		{"different titles", Meta{Title: "Post"}, Meta{Title: "Other"}, Meta{}, true},
+		{"different tags", Meta{Tags: []string{"go"}}, Meta{Tags: []string{"web"}}, Meta{}, true},
+	}
+
+	for _, tt := range tests {
+		err := mergeMeta(&tt.meta, &tt.other)
+		if err != nil && !tt.err {
+			t.Errorf("%s: expected no error, got %v", tt.desc, err)
+		}
+		if err == nil && tt.err {
+			t.Errorf("%s: expected error, got no error", tt.desc)
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(tt.meta, tt.result) {
+			t.Errorf("%s: expected %+v, got %+v", tt.desc, tt.result, tt.meta)
+		}
+	}
+}
@@ -18,6 +18,14 @@ type Meta struct {
 	Tags       []string
 	Categories []string
 	ParsedDate time.Time
+	// Draft posts are not published
+	Draft bool
+	// PublishDate is the date before which the post is not published
+	PublishDate       string
+	ParsedPublishDate time.Time
+	// ExpiryDate is the date after which the post is not published
+	ExpiryDate       string
+	ParsedExpiryDate time.Time
 }
 
 // IndexData is a data container for the landing page
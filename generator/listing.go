@@ -8,7 +8,7 @@ import (
 	"strings"
 
 	"github.com/RomanosTrechlis/blog-gen/config"
-	"github.com/RomanosTrechlis/blog-gen/util/fs"
+	"github.com/RomanosTrechlis/blog-gen/util/url"
 )
 
 // ListingData holds the data for the listing page
@@ -23,30 +23,31 @@ type ListingData struct {
 
 // listingGenerator struct
 type listingGenerator struct {
-	posts                  []*post
-	template               *template.Template
-	siteInfo               *config.SiteInformation
-	destination, pageTitle string
-	pageNum, maxPageNum    int
+	posts               []*post
+	template            *template.Template
+	siteInfo            *config.SiteInformation
+	urls                *url.Builder
+	out                 *output
+	link, pageTitle     string
+	pageNum, maxPageNum int
 }
 
 // Generate starts the listing generation
 func (g *listingGenerator) Generate() (err error) {
 	shortTemplatePath := filepath.Join(g.siteInfo.ThemeFolder, "short.html")
-	short, err := getTemplate(shortTemplatePath)
+	short, err := getTemplate(shortTemplatePath, g.urls)
 	if err != nil {
 		return err
 	}
 	var postBlocks []string
 	for _, post := range g.posts {
 		meta := post.meta
-		link := fmt.Sprintf("/%s/", post.name)
 		ld := ListingData{
 			Title:      meta.Title,
 			Date:       meta.Date,
 			Short:      meta.Short,
-			Link:       link,
-			Tags:       createTags(meta.Tags),
+			Link:       g.urls.RelURL(post.link),
+			Tags:       createTags(g.siteInfo, g.urls, meta.Tags),
 			TimeToRead: calculateTimeToRead(string(post.html)),
 		}
 		block := bytes.Buffer{}
@@ -57,15 +58,8 @@ func (g *listingGenerator) Generate() (err error) {
 		postBlocks = append(postBlocks, block.String())
 	}
 	htmlBlocks := template.HTML(strings.Join(postBlocks, "<br />"))
-	if g.pageNum > 1 {
-		err := fs.CreateFolderIfNotExist(g.destination)
-		if err != nil {
-			return fmt.Errorf("error creating directory at %s: %v", g.destination, err)
-		}
-	}
-
 	c := htmlConfig{
-		path:       g.destination,
+		link:       g.link,
 		pageTitle:  g.pageTitle,
 		pageNum:    g.pageNum,
 		maxPageNum: g.maxPageNum,
@@ -73,6 +67,8 @@ func (g *listingGenerator) Generate() (err error) {
 		temp:       g.template,
 		content:    htmlBlocks,
 		siteInfo:   g.siteInfo,
+		urls:       g.urls,
+		out:        g.out,
 	}
 	err = c.writeHTML()
 	if err != nil {
@@ -81,9 +77,9 @@ func (g *listingGenerator) Generate() (err error) {
 	return nil
 }
 
-func createTags(tags []string) (result []Tag) {
+func createTags(siteInfo *config.SiteInformation, urls *url.Builder, tags []string) (result []Tag) {
 	for _, tag := range tags {
-		result = append(result, Tag{Name: tag, Link: getTagLink(tag)})
+		result = append(result, Tag{Name: tag, Link: urls.RelURL(getTagLink(siteInfo, tag))})
 	}
 	return result
 }
@@ -0,0 +1,113 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/RomanosTrechlis/blog-gen/config"
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
+)
+
+// Option configures a SiteGenerator
+type Option func(g *SiteGenerator) error
+
+// New creates a SiteGenerator with opts. WithConfig is required.
+func New(opts ...Option) (*SiteGenerator, error) {
+	g := &SiteGenerator{log: logger.Discard}
+	for _, opt := range opts {
+		err := opt(g)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if g.siteInfo == nil {
+		return nil, fmt.Errorf("no configuration was given")
+	}
+	return g, nil
+}
+
+// WithConfig sets the configuration of the blog. The generator keeps
+// a copy of siteInfo.
+func WithConfig(siteInfo config.SiteInformation) Option {
+	return func(g *SiteGenerator) error {
+		g.siteInfo = &siteInfo
+		return nil
+	}
+}
+
+// WithSources adds the sources of the posts
+func WithSources(sources ...Source) Option {
+	return func(g *SiteGenerator) error {
+		g.sources = append(g.sources, sources...)
+		return nil
+	}
+}
+
+// WithOutput writes the blog in fs instead of DestFolder. These builds
+// write every page and do not keep previous builds.
+func WithOutput(fs OutputFS) Option {
+	return func(g *SiteGenerator) error {
+		if fs == nil {
+			return fmt.Errorf("output cannot be nil")
+		}
+		g.fs = fs
+		return nil
+	}
+}
+
+// WithLogger sends the messages of the builds to log. The progress of
+// every page is logged at the Debug level and a summary of the build at
+// the Info level.
+func WithLogger(log logger.Logger) Option {
+	return func(g *SiteGenerator) error {
+		if log == nil {
+			log = logger.Discard
+		}
+		g.log = log
+		return nil
+	}
+}
+
+// WithDrafts generates the posts marked as drafts
+func WithDrafts(include bool) Option {
+	return func(g *SiteGenerator) error {
+		g.includeDrafts = include
+		return nil
+	}
+}
+
+// WithFuture generates the posts with a publish date in the future
+func WithFuture(include bool) Option {
+	return func(g *SiteGenerator) error {
+		g.includeFuture = include
+		return nil
+	}
+}
+
+// WithExpired generates the posts with an expiry date in the past
+func WithExpired(include bool) Option {
+	return func(g *SiteGenerator) error {
+		g.includeExpired = include
+		return nil
+	}
+}
+
+// WithConcurrency sets the number of pages generated at the same time.
+// Zero means one for every CPU.
+func WithConcurrency(n int) Option {
+	return func(g *SiteGenerator) error {
+		if n < 0 {
+			return fmt.Errorf("concurrency cannot be negative, got %d", n)
+		}
+		g.concurrency = n
+		return nil
+	}
+}
+
+// WithForce generates every page, instead of reusing the pages of the
+// previous build that did not change
+func WithForce(force bool) Option {
+	return func(g *SiteGenerator) error {
+		g.force = force
+		return nil
+	}
+}
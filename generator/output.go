@@ -0,0 +1,175 @@
+package generator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
+)
+
+// OutputFS is where a build writes the files of the blog
+type OutputFS interface {
+	// Create creates or truncates the file name, a slash separated path
+	// relative to the root of the blog
+	Create(name string) (io.WriteCloser, error)
+}
+
+// DirFS returns an OutputFS that writes in the folder dir
+func DirFS(dir string) OutputFS {
+	return dirFS(dir)
+}
+
+type dirFS string
+
+func (d dirFS) Create(name string) (io.WriteCloser, error) {
+	p := filepath.Join(string(d), filepath.FromSlash(path.Clean("/"+name)))
+	err := os.MkdirAll(filepath.Dir(p), os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("error creating folder %s: %v", filepath.Dir(p), err)
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return nil, fmt.Errorf("error creating file %s: %v", p, err)
+	}
+	return f, nil
+}
+
+// Page is an html page of a built blog
+type Page struct {
+	// URL is the site relative url of the page
+	URL   string
+	Title string
+	// Reused reports whether the page is the one of the previous build
+	Reused bool
+}
+
+// Result describes a finished build
+type Result struct {
+	// Pages are the html pages of the blog, sorted by their url
+	Pages []Page
+	// Posts is the number of published posts
+	Posts int
+	// Reused is the number of posts and pages reused from the previous build
+	Reused int
+	// Warnings are the problems that did not stop the build
+	Warnings []string
+	// Duration is the time of the build and ReadDuration the part of it
+	// spent reading the posts
+	Duration, ReadDuration time.Duration
+}
+
+// output is where a build writes its files and its messages. It records
+// the pages it writes for the result of the build.
+type output struct {
+	fs  OutputFS
+	log logger.Logger
+
+	mu       sync.Mutex
+	pages    []Page
+	warnings []string
+	// read is the time spent reading the posts
+	read time.Duration
+}
+
+func (o *output) debugf(format string, v ...interface{}) {
+	if o == nil {
+		return
+	}
+	logger.Debugf(o.log, format, v...)
+}
+
+func (o *output) infof(format string, v ...interface{}) {
+	if o == nil {
+		return
+	}
+	logger.Infof(o.log, format, v...)
+}
+
+// warnf logs a warning and keeps it for the result of the build
+func (o *output) warnf(format string, v ...interface{}) {
+	if o == nil {
+		return
+	}
+	msg := fmt.Sprintf(format, v...)
+	o.mu.Lock()
+	o.warnings = append(o.warnings, msg)
+	o.mu.Unlock()
+	logger.Warnf(o.log, "%s", msg)
+}
+
+func (o *output) addPage(p Page) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pages = append(o.pages, p)
+}
+
+// writeFile creates the file name and writes it with write
+func (o *output) writeFile(name string, write func(w io.Writer) error) error {
+	w, err := o.fs.Create(name)
+	if err != nil {
+		return err
+	}
+	err = write(w)
+	if e := w.Close(); err == nil && e != nil {
+		err = fmt.Errorf("error writing file %s: %v", name, e)
+	}
+	return err
+}
+
+// copyFile copies the file src to the file name
+func (o *output) copyFile(src, name string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("error reading file %s: %v", src, err)
+	}
+	defer in.Close()
+	return o.writeFile(name, func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		if err != nil {
+			return fmt.Errorf("error copying file %s to %s: %v", src, name, err)
+		}
+		return nil
+	})
+}
+
+// result returns the result of the build of posts, started at start
+func (o *output) result(posts int, cache *buildCache, start time.Time) *Result {
+	pages := append([]Page(nil), o.pages...)
+	reused := 0
+	if cache != nil {
+		pages = append(pages, cache.pages...)
+		reused = cache.reused
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].URL < pages[j].URL
+	})
+	return &Result{
+		Pages:        pages,
+		Posts:        posts,
+		Reused:       reused,
+		Warnings:     append([]string(nil), o.warnings...),
+		Duration:     time.Since(start),
+		ReadDuration: o.read,
+	}
+}
+
+// summary logs the counts and the durations of the result r
+func (o *output) summary(r *Result) {
+	if o.log == nil {
+		return
+	}
+	o.log.Log(logger.Info, "Finished generating Site...", logger.Fields{
+		"pages":    len(r.Pages),
+		"posts":    r.Posts,
+		"reused":   r.Reused,
+		"warnings": len(r.Warnings),
+		"duration": r.Duration.Round(time.Millisecond),
+		"read":     r.ReadDuration.Round(time.Millisecond),
+	})
+}
@@ -0,0 +1,138 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RomanosTrechlis/blog-gen/config"
+)
+
+// memFS is an OutputFS that keeps the files in memory
+type memFS struct {
+	mu    sync.Mutex
+	files map[string]*bytes.Buffer
+}
+
+type memFile struct {
+	*bytes.Buffer
+}
+
+func (memFile) Close() error { return nil }
+
+func (m *memFS) Create(name string) (io.WriteCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b := &bytes.Buffer{}
+	m.files[name] = b
+	return memFile{b}, nil
+}
+
+func TestBuildOutput(t *testing.T) {
+	root, err := ioutil.TempDir("", "blog-gen-output")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	theme := filepath.Join(root, "theme")
+	writeFile(t, filepath.Join(theme, "template.html"), "<title>{{.HTMLTitle}}</title>{{.Content}}")
+	writeFile(t, filepath.Join(theme, "short.html"), "{{.Title}}")
+	writeFile(t, filepath.Join(theme, "tags.html"), "{{range .}}{{.Name}}{{end}}")
+	writeFile(t, filepath.Join(theme, "categories.html"), "{{range .}}{{.Name}}{{end}}")
+	var paths []string
+	for _, name := range []string{"first", "second"} {
+		path := filepath.Join(root, "posts", name+".md")
+		writeFile(t, path, fmt.Sprintf("---\ntitle: %s\ndate: 2020-01-02\ntags: [go]\n---\n# %s", name, name))
+		paths = append(paths, path)
+	}
+	readme := filepath.Join(root, "posts", "README.md")
+	writeFile(t, readme, "# Posts")
+	paths = append(paths, readme)
+
+	titles := []string{"One", "Two"}
+	outputs := make([]*memFS, len(titles))
+	results := make([]*Result, len(titles))
+	errs := make([]error, len(titles))
+	var wg sync.WaitGroup
+	for i, title := range titles {
+		outputs[i] = &memFS{files: make(map[string]*bytes.Buffer)}
+		g, err := New(
+			WithConfig(config.SiteInformation{
+				BlogTitle:         title,
+				BlogURL:           "https://example.com/blog/",
+				DateFormat:        "2006-01-02",
+				NumPostsFrontPage: 10,
+				ThemeFolder:       theme,
+				Permalinks:        config.Permalinks{Post: "/:slug/", Tag: "/tags/:slug/", Category: "/categories/:slug/"},
+			}),
+			WithSources(Source{Paths: paths}),
+			WithOutput(outputs[i]),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = g.Build(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	for i, title := range titles {
+		if errs[i] != nil {
+			t.Fatalf("expected no error, got %v", errs[i])
+		}
+		var urls []string
+		for _, p := range results[i].Pages {
+			urls = append(urls, p.URL)
+		}
+		expected := "/ /archive/ /categories/ /first/ /second/ /tags/ /tags/go/"
+		if strings.Join(urls, " ") != expected || results[i].Posts != 2 {
+			t.Errorf("expected the pages %s of 2 posts, got %v of %d", expected, urls, results[i].Posts)
+		}
+		if len(results[i].Warnings) != 1 || !strings.Contains(results[i].Warnings[0], readme) {
+			t.Errorf("expected a warning for %s, got %v", readme, results[i].Warnings)
+		}
+		index, ok := outputs[i].files["index.html"]
+		if !ok || !strings.Contains(index.String(), "<title>"+title+"</title>") {
+			t.Errorf("expected the index of %s, got %v", title, index)
+		}
+		for _, name := range []string{"first/index.html", "index.xml", "sitemap.xml"} {
+			if _, ok := outputs[i].files[name]; !ok {
+				t.Errorf("expected file %s in the output of %s", name, title)
+			}
+		}
+	}
+}
+
+func TestOutputWithoutLogger(t *testing.T) {
+	o := &output{}
+	o.debugf("page %d", 1)
+	o.infof("page %d", 2)
+	o.warnf("page %d", 3)
+	o.summary(o.result(1, nil, time.Now()))
+	if len(o.warnings) != 1 || o.warnings[0] != "page 3" {
+		t.Errorf("expected the warning 'page 3', got %v", o.warnings)
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(WithSources(Source{}))
+	if err == nil {
+		t.Errorf("expected an error without a configuration")
+	}
+	_, err = New(WithConfig(config.SiteInformation{}), WithConcurrency(-1))
+	if err == nil {
+		t.Errorf("expected an error for negative concurrency")
+	}
+}
@@ -0,0 +1,159 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	neturl "net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/RomanosTrechlis/blog-gen/config"
+)
+
+// permalinkToken matches the tokens of the permalink patterns
+var permalinkToken = regexp.MustCompile(`:(year|month|day|slug|section|category)`)
+
+// postLink returns the site relative url of the post
+func postLink(siteInfo *config.SiteInformation, p *post) string {
+	category := ""
+	if len(p.meta.Categories) > 0 {
+		category = strings.ToLower(p.meta.Categories[0])
+	}
+	date := p.meta.ParsedDate
+	return expandPermalink(siteInfo.Permalinks.Post, map[string]string{
+		"year":     fmt.Sprintf("%04d", date.Year()),
+		"month":    fmt.Sprintf("%02d", int(date.Month())),
+		"day":      fmt.Sprintf("%02d", date.Day()),
+		"slug":     p.name,
+		"section":  p.section,
+		"category": category,
+	}, siteInfo.UglyURLs)
+}
+
+// getTagLink returns the site relative url of the page of tag
+func getTagLink(siteInfo *config.SiteInformation, tag string) string {
+	values := map[string]string{"slug": strings.ToLower(tag)}
+	return expandPermalink(siteInfo.Permalinks.Tag, values, siteInfo.UglyURLs)
+}
+
+// getCatLink returns the site relative url of the page of cat
+func getCatLink(siteInfo *config.SiteInformation, cat string) string {
+	values := map[string]string{"slug": strings.ToLower(cat)}
+	return expandPermalink(siteInfo.Permalinks.Category, values, siteInfo.UglyURLs)
+}
+
+// getTagIndexLink returns the site relative url of the page of all tags
+func getTagIndexLink(siteInfo *config.SiteInformation) string {
+	return indexLink(siteInfo.Permalinks.Tag, "/tags/")
+}
+
+// getCatIndexLink returns the site relative url of the page of all
+// categories
+func getCatIndexLink(siteInfo *config.SiteInformation) string {
+	return indexLink(siteInfo.Permalinks.Category, "/categories/")
+}
+
+// indexLink returns the folder of the pages of pattern, up to its first
+// token, e.g. /topics/ for /topics/:slug/. A pattern whose pages are at
+// the root of the site, which is the home page, uses fallback.
+func indexLink(pattern, fallback string) string {
+	if loc := permalinkToken.FindStringIndex(pattern); loc != nil {
+		pattern = pattern[:loc[0]]
+	}
+	// the text after the last slash is part of the file of the pages
+	pattern = pattern[:strings.LastIndex(pattern, "/")+1]
+	link := path.Clean("/" + pattern)
+	if link == "/" {
+		return fallback
+	}
+	return link + "/"
+}
+
+// expandPermalink replaces the tokens of pattern with values. The
+// slashes around empty values are merged. Patterns that end with a
+// slash are pages in a folder, unless ugly is set.
+func expandPermalink(pattern string, values map[string]string, ugly bool) string {
+	link := permalinkToken.ReplaceAllStringFunc(pattern, func(token string) string {
+		return values[token[1:]]
+	})
+	isFolder := strings.HasSuffix(link, "/")
+	link = path.Clean("/" + link)
+	if link == "/" {
+		return link
+	}
+	if !isFolder {
+		return link
+	}
+	if ugly {
+		return link + ".html"
+	}
+	return link + "/"
+}
+
+// staticLink returns the site relative url of a static page written to
+// the file to, relative to the destination folder
+func staticLink(to string) string {
+	link := path.Clean("/" + filepath.ToSlash(to))
+	if path.Base(link) == "index.html" {
+		return strings.TrimSuffix(link, "index.html")
+	}
+	return link
+}
+
+// isFolderLink reports whether the page of link is an index.html in a folder
+func isFolderLink(link string) bool {
+	return strings.HasSuffix(link, "/")
+}
+
+// assetLink returns the folder of the images and artifacts of the page of
+// link. Pages that are files keep them in a folder named after the file.
+func assetLink(link string) string {
+	if isFolderLink(link) {
+		return link
+	}
+	return strings.TrimSuffix(link, path.Ext(link)) + "/"
+}
+
+// pageFile returns the slash separated file of the page of link,
+// relative to the root of the blog
+func pageFile(link string) string {
+	p := strings.TrimPrefix(link, "/")
+	if isFolderLink(link) {
+		return p + "index.html"
+	}
+	return p
+}
+
+// rebaseLinks prefixes the relative src and href attributes of html with
+// base, for pages that are not in the folder of their images.
+func rebaseLinks(html []byte, base string) ([]byte, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(html))
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing html: %v", err)
+	}
+	for _, attr := range []string{"src", "href"} {
+		doc.Find("[" + attr + "]").Each(func(i int, s *goquery.Selection) {
+			value, _ := s.Attr(attr)
+			if isRelative(value) {
+				s.SetAttr(attr, base+value)
+			}
+		})
+	}
+	rebased, err := doc.Find("body").Html()
+	if err != nil {
+		return nil, fmt.Errorf("error while generating html: %v", err)
+	}
+	return []byte(rebased), nil
+}
+
+// isRelative reports whether link is relative to the folder of the page
+func isRelative(link string) bool {
+	if link == "" || strings.HasPrefix(link, "/") || strings.HasPrefix(link, "#") || strings.HasPrefix(link, "?") {
+		return false
+	}
+	u, err := neturl.Parse(link)
+	return err == nil && u.Scheme == "" && u.Host == ""
+}
@@ -0,0 +1,109 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RomanosTrechlis/blog-gen/config"
+)
+
+func TestExpandPermalink(t *testing.T) {
+	values := map[string]string{"year": "2020", "slug": "my-post", "section": ""}
+	var tests = []struct {
+		pattern string
+		ugly    bool
+		link    string
+	}{
+		{"/:section/:slug/", false, "/my-post/"},
+		{"/:section/:slug/", true, "/my-post.html"},
+		{"/:year/:slug/", false, "/2020/my-post/"},
+		{"/:year/:slug.html", false, "/2020/my-post.html"},
+		{"/:section/", false, "/"},
+	}
+
+	for _, tt := range tests {
+		link := expandPermalink(tt.pattern, values, tt.ugly)
+		if link != tt.link {
+			t.Errorf("%s: expected %s, got %s", tt.pattern, tt.link, link)
+		}
+	}
+}
+
+func TestIndexLink(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		link    string
+	}{
+		{"/tags/:slug/", "/tags/"},
+		{"/topics/:slug.html", "/topics/"},
+		{"/blog/topics/tag-:slug/", "/blog/topics/"},
+		{"/:slug/", "/tags/"},
+		{"tag-:slug", "/tags/"},
+	}
+
+	for _, tt := range tests {
+		link := indexLink(tt.pattern, "/tags/")
+		if link != tt.link {
+			t.Errorf("%s: expected %s, got %s", tt.pattern, tt.link, link)
+		}
+	}
+}
+
+func TestPostLink(t *testing.T) {
+	siteInfo := &config.SiteInformation{
+		Permalinks: config.Permalinks{Post: "/:year/:month/:day/:category/:section/:slug/"},
+	}
+	meta := &Meta{
+		Categories: []string{"Go"},
+		ParsedDate: time.Date(2020, time.March, 5, 0, 0, 0, 0, time.UTC),
+	}
+	p := &post{name: "my-post", section: "notes", meta: meta}
+
+	link := postLink(siteInfo, p)
+	if link != "/2020/03/05/go/notes/my-post/" {
+		t.Errorf("expected /2020/03/05/go/notes/my-post/, got %s", link)
+	}
+}
+
+func TestLinkPaths(t *testing.T) {
+	var tests = []struct {
+		link  string
+		asset string
+		file  string
+	}{
+		{"/", "/", "index.html"},
+		{"/my-post/", "/my-post/", "my-post/index.html"},
+		{"/2020/my-post.html", "/2020/my-post/", "2020/my-post.html"},
+	}
+
+	for _, tt := range tests {
+		if asset := assetLink(tt.link); asset != tt.asset {
+			t.Errorf("%s: expected asset folder %s, got %s", tt.link, tt.asset, asset)
+		}
+		if file := pageFile(tt.link); file != tt.file {
+			t.Errorf("%s: expected file %s, got %s", tt.link, tt.file, file)
+		}
+	}
+
+	if link := staticLink("about/index.html"); link != "/about/" {
+		t.Errorf("expected /about/, got %s", link)
+	}
+	if link := staticLink("robots.txt"); link != "/robots.txt" {
+		t.Errorf("expected /robots.txt, got %s", link)
+	}
+}
+
+func TestRebaseLinks(t *testing.T) {
+	html := `<p><img src="images/a.png"/><a href="/about/">a</a><a href="https://go.dev">b</a><a href="#top">c</a></p>`
+	rebased, err := rebaseLinks([]byte(html), "my-post/")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s := string(rebased)
+	for _, want := range []string{`src="my-post/images/a.png"`, `href="/about/"`, `href="https://go.dev"`, `href="#top"`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("expected %s in %s", want, s)
+		}
+	}
+}
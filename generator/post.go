@@ -6,12 +6,12 @@ import (
 	"html/template"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/RomanosTrechlis/blog-gen/config"
-	"github.com/RomanosTrechlis/blog-gen/util/fs"
 	"github.com/RomanosTrechlis/blog-gen/util/url"
 	"github.com/russross/blackfriday"
 	"github.com/sourcegraph/syntaxhighlight"
@@ -19,7 +19,17 @@ import (
 
 // post holds data for a post
 type post struct {
-	name      string
+	name string
+	// path is the file or the folder the post was read from
+	path string
+	// section is the prefix of the source of the post
+	section string
+	// link is the site relative url of the post
+	link string
+	// hash is the hash of the files of the post
+	hash string
+	// dir is the folder of the post, empty for single file posts
+	dir       string
 	html      []byte
 	meta      *Meta
 	imagesDir string
@@ -31,60 +41,70 @@ type byDateDesc []*post
 
 // postGenerator object
 type postGenerator struct {
-	post        *post
-	siteInfo    *config.SiteInformation
-	template    *template.Template
-	destination string
+	post     *post
+	siteInfo *config.SiteInformation
+	template *template.Template
+	urls     *url.Builder
+	out      *output
 }
 
 // Generate generates a post
 func (g *postGenerator) Generate() (err error) {
 	post := g.post
-	fmt.Printf("\tGenerating Post: %s...\n", post.meta.Title)
-	staticPath := filepath.Join(g.destination, post.name)
-	err = fs.CreateFolderIfNotExist(staticPath)
-	if err != nil {
-		return fmt.Errorf("error creating directory at %s: %v", staticPath, err)
-	}
+	g.out.debugf("Generating Post: %s...", post.meta.Title)
+	// assets is the folder of the images and the artifacts of the post
+	assets := strings.TrimPrefix(assetLink(post.link), "/")
 	if post.imagesDir != "" {
-		err := g.copyImagesDir(post.imagesDir, staticPath)
+		err := g.copyImagesDir(post.imagesDir, assets+"images/")
 		if err != nil {
 			return err
 		}
 	}
 
+	html := post.html
+	if !isFolderLink(post.link) {
+		html, err = rebaseLinks(html, path.Base(assets)+"/")
+		if err != nil {
+			return fmt.Errorf("error rebasing links of post %s: %v", post.meta.Title, err)
+		}
+	}
 	c := htmlConfig{
-		path:       url.ChangePathToUrl(staticPath),
+		link:       post.link,
 		pageTitle:  post.meta.Title,
 		pageNum:    0,
 		maxPageNum: 0,
 		isPost:     true,
 		temp:       g.template,
-		content:    template.HTML(string(post.html)),
+		content:    template.HTML(string(html)),
 		siteInfo:   g.siteInfo,
+		urls:       g.urls,
+		out:        g.out,
 	}
 	err = c.writeHTML()
 	if err != nil {
 		return err
 	}
 
-	err = g.copyAdditionalArtifacts(staticPath, post.name)
+	err = g.copyAdditionalArtifacts(assets, post.dir)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("\tFinished generating Post: %s...\n", post.meta.Title)
+	g.out.debugf("Finished generating Post: %s...", post.meta.Title)
 	return nil
 }
 
-func (g *postGenerator) copyAdditionalArtifacts(path, postName string) (err error) {
-	src := filepath.Join(g.siteInfo.TempFolder, postName, "artifacts")
-	files, err := ioutil.ReadDir(src)
+func (g *postGenerator) copyAdditionalArtifacts(folder, postDir string) (err error) {
+	if postDir == "" {
+		return nil
+	}
+	dir := filepath.Join(postDir, "artifacts")
+	files, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return nil
 	}
 	for _, file := range files {
-		src = filepath.Join(src, file.Name())
-		err := fs.CopyFile(src, path)
+		src := filepath.Join(dir, file.Name())
+		err := g.out.copyFile(src, folder+file.Name())
 		if err != nil {
 			return err
 		}
@@ -92,19 +112,14 @@ func (g *postGenerator) copyAdditionalArtifacts(path, postName string) (err erro
 	return nil
 }
 
-func (*postGenerator) copyImagesDir(source, destination string) (err error) {
-	path := filepath.Join(destination, "images")
-	err = fs.CreateFolderIfNotExist(path)
-	if err != nil {
-		return fmt.Errorf("error creating images directory at %s: %v", path, err)
-	}
+func (g *postGenerator) copyImagesDir(source, folder string) (err error) {
 	files, err := ioutil.ReadDir(source)
 	if err != nil {
-		return fmt.Errorf("error reading directory %s: %v", path, err)
+		return fmt.Errorf("error reading directory %s: %v", source, err)
 	}
 	for _, file := range files {
 		src := filepath.Join(source, file.Name())
-		err := fs.CopyFile(src, path)
+		err := g.out.copyFile(src, folder+file.Name())
 		if err != nil {
 			return err
 		}
@@ -112,13 +127,21 @@ func (*postGenerator) copyImagesDir(source, destination string) (err error) {
 	return nil
 }
 
-func getHTML(path string) (html []byte, err error) {
-	filePath := filepath.Join(path, "post.md")
-	input, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("error while reading file %s: %v", filePath, err)
+// hasAssets reports whether the post has images or artifacts
+func (p *post) hasAssets() bool {
+	if p.imagesDir != "" {
+		return true
 	}
-	html = blackfriday.MarkdownCommon(input)
+	if p.dir == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(p.dir, "artifacts"))
+	return err == nil
+}
+
+// getHTML renders the markdown of the post in filePath
+func getHTML(filePath string, input []byte) (html []byte, err error) {
+	html = blackfriday.Run(input)
 	replaced, err := replaceCodeParts(html)
 	if err != nil {
 		return nil, fmt.Errorf("error during syntax highlighting of %s: %v", filePath, err)
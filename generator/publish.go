@@ -0,0 +1,185 @@
+package generator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// buildTimeFormat names the kept builds, so that they sort by date
+const buildTimeFormat = "20060102-150405.000000000"
+
+// stagingFolder returns the folder the blog of dest is generated in
+// before it replaces dest
+func stagingFolder(dest string) string {
+	return siblingFolder(dest, "staging")
+}
+
+// buildsFolder returns the folder that keeps the previous builds of dest
+func buildsFolder(dest string) string {
+	return siblingFolder(dest, "builds")
+}
+
+// siblingFolder returns a hidden folder next to dest, e.g.
+// .public.staging for public
+func siblingFolder(dest, kind string) string {
+	dest = filepath.Clean(dest)
+	return filepath.Join(filepath.Dir(dest), fmt.Sprintf(".%s.%s", filepath.Base(dest), kind))
+}
+
+// publish makes dest the generated blog of staging. The builds are kept
+// in the builds folder and dest is a symlink to one of them, which is
+// replaced with a single rename, so dest always holds a complete build.
+// The previous build stays in the builds folder until pruneBuilds removes
+// it. A dest that is a folder, e.g. of an older version, is moved in the
+// builds folder first and between the two renames dest does not exist.
+func publish(staging, dest string) error {
+	dest = filepath.Clean(dest)
+	builds := buildsFolder(dest)
+	err := os.MkdirAll(builds, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("error creating folder %s: %v", builds, err)
+	}
+	name := time.Now().Format(buildTimeFormat)
+	build := filepath.Join(builds, name)
+	err = os.Rename(staging, build)
+	if err != nil {
+		return fmt.Errorf("error moving build %s to %s: %v", staging, build, err)
+	}
+
+	info, err := os.Lstat(dest)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading folder %s: %v", dest, err)
+	}
+	folder := ""
+	if err == nil && info.Mode()&os.ModeSymlink == 0 {
+		folder = filepath.Join(builds, info.ModTime().Format(buildTimeFormat))
+		err = os.Rename(dest, folder)
+		if err != nil {
+			return fmt.Errorf("error moving previous build %s: %v", dest, err)
+		}
+	}
+
+	err = linkBuild(dest, name)
+	if err != nil && folder != "" {
+		if e := os.Rename(folder, dest); e != nil {
+			return fmt.Errorf("%v, and the previous build is left in %s: %v", err, folder, e)
+		}
+	}
+	return err
+}
+
+// linkBuild points dest to the build name. The new symlink is created
+// next to dest and renamed over it, which replaces dest at once.
+func linkBuild(dest, name string) error {
+	link := siblingFolder(dest, "link")
+	err := os.Remove(link)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing %s: %v", link, err)
+	}
+	// the target is relative, so that the folders can be moved together
+	target := filepath.Join(filepath.Base(buildsFolder(dest)), name)
+	err = os.Symlink(target, link)
+	if err != nil {
+		return fmt.Errorf("error linking build %s: %v", name, err)
+	}
+	err = os.Rename(link, dest)
+	if err != nil {
+		os.Remove(link)
+		return fmt.Errorf("error replacing %s with build %s: %v", dest, name, err)
+	}
+	return nil
+}
+
+// currentBuild returns the name of the build dest points to, or an empty
+// name when dest is not a symlink to a build
+func currentBuild(dest string) string {
+	target, err := os.Readlink(dest)
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+// pruneBuilds removes the previous builds of dest, except the newest keep
+func pruneBuilds(dest string, keep int) error {
+	builds, err := Builds(dest)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(builds)-keep; i++ {
+		folder := filepath.Join(buildsFolder(dest), builds[i])
+		err = os.RemoveAll(folder)
+		if err != nil {
+			return fmt.Errorf("error removing previous build %s: %v", folder, err)
+		}
+	}
+	return nil
+}
+
+// Builds returns the names of the previous builds of dest, oldest first.
+// The build dest points to is not one of them.
+func Builds(dest string) ([]string, error) {
+	folder := buildsFolder(dest)
+	infos, err := ioutil.ReadDir(folder)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading folder %s: %v", folder, err)
+	}
+	current := currentBuild(dest)
+	builds := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if info.IsDir() && info.Name() != current {
+			builds = append(builds, info.Name())
+		}
+	}
+	sort.Strings(builds)
+	return builds, nil
+}
+
+// Rollback points dest to the newest of its previous builds and returns
+// the name of that build. The replaced build is removed, so every
+// rollback goes one build further back. The build cache describes the
+// replaced build, so it is removed too.
+func Rollback(dest string) (string, error) {
+	dest = filepath.Clean(dest)
+	builds, err := Builds(dest)
+	if err != nil {
+		return "", err
+	}
+	if len(builds) == 0 {
+		return "", fmt.Errorf("there are no previous builds of %s", dest)
+	}
+	latest := builds[len(builds)-1]
+	replaced := ""
+	if current := currentBuild(dest); current != "" {
+		replaced = filepath.Join(buildsFolder(dest), current)
+	} else {
+		// a folder of an older version is not a build and cannot be
+		// replaced by a rename
+		err = os.RemoveAll(dest)
+		if err != nil {
+			return "", fmt.Errorf("error removing folder %s: %v", dest, err)
+		}
+	}
+	err = linkBuild(dest, latest)
+	if err != nil {
+		return "", err
+	}
+	if replaced != "" {
+		err = os.RemoveAll(replaced)
+		if err != nil {
+			return "", fmt.Errorf("error removing replaced build %s: %v", replaced, err)
+		}
+	}
+	err = os.Remove(cacheFile(dest))
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("error removing build cache: %v", err)
+	}
+	return latest, nil
+}
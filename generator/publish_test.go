@@ -0,0 +1,134 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPublish(t *testing.T) {
+	root, err := ioutil.TempDir("", "blog-gen-publish")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+	dest := filepath.Join(root, "public")
+
+	for _, content := range []string{"1", "2", "3", "4"} {
+		staging := stagingFolder(dest)
+		writeFile(t, filepath.Join(staging, "index.html"), content)
+		err = publish(staging, dest)
+		if err == nil {
+			err = pruneBuilds(dest, 2)
+		}
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		expectContent(t, filepath.Join(dest, "index.html"), content)
+		info, err := os.Lstat(dest)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Fatalf("expected %s to be a symlink, got %v", dest, err)
+		}
+	}
+	if _, err := os.Stat(stagingFolder(dest)); !os.IsNotExist(err) {
+		t.Errorf("expected staging folder to be moved, got %v", err)
+	}
+
+	builds, err := Builds(dest)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(builds) != 2 {
+		t.Fatalf("expected 2 kept builds, got %v", builds)
+	}
+
+	for _, content := range []string{"3", "2"} {
+		_, err = Rollback(dest)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		expectContent(t, filepath.Join(dest, "index.html"), content)
+	}
+	_, err = Rollback(dest)
+	if err == nil {
+		t.Errorf("expected error without kept builds")
+	}
+	infos, err := ioutil.ReadDir(buildsFolder(dest))
+	if err != nil || len(infos) != 1 {
+		t.Errorf("expected only the current build, got %v %v", infos, err)
+	}
+}
+
+func TestPublishFolder(t *testing.T) {
+	root, err := ioutil.TempDir("", "blog-gen-publish")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+	dest := filepath.Join(root, "public")
+	// the folder of an older version becomes a previous build
+	writeFile(t, filepath.Join(dest, "index.html"), "1")
+	staging := stagingFolder(dest)
+	writeFile(t, filepath.Join(staging, "index.html"), "2")
+	err = publish(staging, dest)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectContent(t, filepath.Join(dest, "index.html"), "2")
+
+	_, err = Rollback(dest)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectContent(t, filepath.Join(dest, "index.html"), "1")
+}
+
+func TestPublishFailure(t *testing.T) {
+	root, err := ioutil.TempDir("", "blog-gen-publish")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+	dest := filepath.Join(root, "public")
+	writeFile(t, filepath.Join(dest, "index.html"), "1")
+
+	// the staging folder is missing, so publish fails before dest is touched
+	err = publish(stagingFolder(dest), dest)
+	if err == nil {
+		t.Fatalf("expected error without a staging folder")
+	}
+	expectContent(t, filepath.Join(dest, "index.html"), "1")
+
+	staging := stagingFolder(dest)
+	writeFile(t, filepath.Join(staging, "index.html"), "2")
+	err = publish(staging, dest)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	err = publish(stagingFolder(dest), dest)
+	if err == nil {
+		t.Fatalf("expected error without a staging folder")
+	}
+	expectContent(t, filepath.Join(dest, "index.html"), "2")
+}
+
+func writeFile(t *testing.T, path, content string) {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err == nil {
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+	}
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func expectContent(t *testing.T, path, content string) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(b) != content {
+		t.Errorf("expected '%s' in %s, got '%s'", content, path, b)
+	}
+}
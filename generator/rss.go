@@ -2,28 +2,28 @@ package generator
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
 	"time"
 
 	"github.com/RomanosTrechlis/blog-gen/config"
+	"github.com/RomanosTrechlis/blog-gen/util/url"
 	"github.com/beevik/etree"
 )
 
 // rssGenerator object
 type rssGenerator struct {
-	posts       []*post
-	destination string
-	siteInfo    *config.SiteInformation
+	posts    []*post
+	siteInfo *config.SiteInformation
+	urls     *url.Builder
+	out      *output
 }
 
 const rssDateFormat = "02 Jan 2006 15:04 -0700"
 
 // Generate creates an RSS feed
 func (g *rssGenerator) Generate() (err error) {
-	fmt.Println("\tGenerating RSS...")
+	g.out.debugf("Generating RSS...")
 	posts := g.posts
-	destination := g.destination
 	doc := etree.NewDocument()
 	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
 	rss := doc.CreateElement("rss")
@@ -33,49 +33,40 @@ func (g *rssGenerator) Generate() (err error) {
 	siteInfo := g.siteInfo
 
 	channel.CreateElement("title").SetText(siteInfo.BlogTitle)
-	channel.CreateElement("link").SetText(siteInfo.BlogURL)
+	channel.CreateElement("link").SetText(g.urls.AbsURL("/"))
 	channel.CreateElement("language").SetText(siteInfo.BlogLanguage)
 	channel.CreateElement("description").SetText(siteInfo.BlogDescription)
 	channel.CreateElement("lastBuildDate").SetText(time.Now().Format(rssDateFormat))
 
 	atomLink := channel.CreateElement("atom:link")
-	atomLink.CreateAttr("href", fmt.Sprintf("%s/index.xml", siteInfo.BlogURL))
+	atomLink.CreateAttr("href", g.urls.AbsURL("/index.xml"))
 	atomLink.CreateAttr("rel", "self")
 	atomLink.CreateAttr("type", "application/rss+xml")
 
 	for _, post := range posts {
-		err := g.addItem(channel, post)
-		if err != nil {
-			return err
-		}
+		g.addItem(channel, post)
 	}
 
-	filePath := filepath.Join(destination, "index.xml")
-	f, err := os.Create(filePath)
+	err = g.out.writeFile("index.xml", func(w io.Writer) error {
+		_, err := doc.WriteTo(w)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("error creating file %s: %v", filePath, err)
+		return fmt.Errorf("error writing to file index.xml: %v", err)
 	}
-	f.Close()
-	err = doc.WriteToFile(filePath)
-	if err != nil {
-		return fmt.Errorf("error writing to file %s: %v", filePath, err)
-	}
-	fmt.Println("\tFinished generating RSS...")
+	g.out.debugf("Finished generating RSS...")
 	return nil
 }
 
-func (g *rssGenerator) addItem(element *etree.Element, post *post) (err error) {
-	path := fmt.Sprintf("%s/%s/", g.siteInfo.BlogURL, post.name[1:])
+// addItem adds post to the channel element. The pubDate is the date the
+// posts are sorted and filtered by.
+func (g *rssGenerator) addItem(element *etree.Element, post *post) {
+	path := g.urls.AbsURL(post.link)
 	meta := post.meta
 	item := element.CreateElement("item")
 	item.CreateElement("title").SetText(meta.Title)
 	item.CreateElement("link").SetText(path)
 	item.CreateElement("guid").SetText(path)
-	pubDate, err := time.Parse(g.siteInfo.DateFormat, meta.Date)
-	if err != nil {
-		return fmt.Errorf("error parsing date %s: %v", meta.Date, err)
-	}
-	item.CreateElement("pubDate").SetText(pubDate.Format(rssDateFormat))
+	item.CreateElement("pubDate").SetText(meta.ParsedDate.Format(rssDateFormat))
 	item.CreateElement("description").SetText(string(post.html))
-	return nil
 }
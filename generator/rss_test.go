@@ -0,0 +1,37 @@
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RomanosTrechlis/blog-gen/config"
+	"github.com/RomanosTrechlis/blog-gen/util/url"
+)
+
+func TestRSSPubDate(t *testing.T) {
+	urls, err := url.NewBuilder("https://example.com/")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	out := &memFS{files: make(map[string]*bytes.Buffer)}
+	date := time.Date(2020, time.January, 2, 0, 0, 0, 0, time.FixedZone("EET", 2*60*60))
+	g := &rssGenerator{
+		posts: []*post{{
+			link: "/first/",
+			meta: &Meta{Title: "first", Date: "2020-01-02", ParsedDate: date},
+		}},
+		siteInfo: &config.SiteInformation{DateFormat: "2006-01-02"},
+		urls:     urls,
+		out:      &output{fs: out},
+	}
+	err = g.Generate()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := "<pubDate>02 Jan 2020 00:00 +0200</pubDate>"
+	if !strings.Contains(out.files["index.xml"].String(), expected) {
+		t.Errorf("expected %s, got %s", expected, out.files["index.xml"])
+	}
+}
@@ -2,81 +2,316 @@ package generator
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"io/ioutil"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/RomanosTrechlis/blog-gen/config"
-	"github.com/RomanosTrechlis/blog-gen/util/fs"
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
 	"github.com/RomanosTrechlis/blog-gen/util/url"
 	"gopkg.in/yaml.v2"
 )
 
-// siteGenerator object
-type siteGenerator struct {
-	Sources  []string
-	SiteInfo *config.SiteInformation
+// Source is a group of posts that come from the same datasource
+type Source struct {
+	// Paths are the post folders and the markdown files of the datasource
+	Paths []string
+	// Prefix is prepended to the name of every post
+	Prefix string
+	// Tags and Categories are added to the ones of every post
+	Tags       []string
+	Categories []string
 }
 
-// New creates a new SiteGenerator
-func NewSiteGenerator(sources []string, siteInfo *config.SiteInformation) *siteGenerator {
-	return &siteGenerator{sources, siteInfo}
+// SiteGenerator builds a blog. It is created with New and every call of
+// Build is an independent build, so a SiteGenerator can run several
+// builds at the same time, as long as they write in different places.
+type SiteGenerator struct {
+	sources  []Source
+	siteInfo *config.SiteInformation
+	// includeDrafts generates the posts marked as drafts
+	includeDrafts bool
+	// includeFuture generates the posts with a publish date in the future
+	includeFuture bool
+	// includeExpired generates the posts with an expiry date in the past
+	includeExpired bool
+	// concurrency is the number of pages generated at the same time.
+	// Zero means one for every CPU.
+	concurrency int
+	// force generates every page, instead of reusing the pages of the
+	// previous build that did not change
+	force bool
+	// fs is where the blog is written. Without one the blog is written in
+	// DestFolder.
+	fs  OutputFS
+	log logger.Logger
+
+	cache *buildCache
+	out   *output
+}
+
+// Generate builds the blog, as Build does without a context
+func (g *SiteGenerator) Generate() (err error) {
+	_, err = g.Build(context.Background())
+	return err
 }
 
-var templatePath string
+// Build generates the blog until ctx is canceled. A canceled build returns
+// the error of ctx. Without an OutputFS the blog is generated in a staging
+// folder, which replaces DestFolder only when every page is generated,
+// and the unchanged pages of the previous build are reused.
+func (g *SiteGenerator) Build(ctx context.Context) (*Result, error) {
+	start := time.Now()
+	build := *g
+	build.out = &output{fs: g.fs, log: g.log}
+	build.out.infof("Generating Site...")
+	if g.fs != nil {
+		posts, err := build.generate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result := build.out.result(posts, nil, start)
+		build.out.summary(result)
+		return result, nil
+	}
 
-// Generate starts the static blog generation
-func (g *siteGenerator) Generate() (err error) {
-	templatePath = filepath.Join(g.SiteInfo.ThemeFolder, "template.html")
-	fmt.Println("Generating Site...")
-	err = clearAndCreateDestination(g.SiteInfo.DestFolder)
+	dest := g.siteInfo.DestFolder
+	staging := stagingFolder(dest)
+	err := os.RemoveAll(staging)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("error removing folder %s: %v", staging, err)
+	}
+	site, err := siteHash(g.siteInfo)
+	if err != nil {
+		return nil, err
 	}
+	build.cache = loadCache(dest, staging, site, g.force)
+	build.out.fs = DirFS(staging)
 
-	err = clearAndCreateDestination(filepath.Join(g.SiteInfo.DestFolder, "archive"))
+	posts, err := build.generate(ctx)
 	if err != nil {
-		return err
+		os.RemoveAll(staging)
+		return nil, err
+	}
+	err = publish(staging, dest)
+	if err != nil {
+		return nil, err
+	}
+	err = pruneBuilds(dest, g.siteInfo.KeepBuilds)
+	if err != nil {
+		build.out.warnf("Couldn't remove the previous builds: %v", err)
+	}
+	if build.cache.reused > 0 {
+		build.out.debugf("Reused %d unchanged pages of the previous build...", build.cache.reused)
+	}
+	err = build.cache.save(dest)
+	if err != nil {
+		build.out.warnf("The next build will generate every page: %v", err)
 	}
+	result := build.out.result(posts, build.cache, start)
+	build.out.summary(result)
+	return result, nil
+}
 
-	t, err := getTemplate(templatePath)
+// generate writes the blog and returns the number of its posts
+func (g *SiteGenerator) generate(ctx context.Context) (int, error) {
+	urls, err := url.NewBuilder(g.siteInfo.BlogURL)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	posts := make([]*post, 0)
-	for _, path := range g.Sources {
-		post, err := g.newPost(path)
-		if err != nil {
-			return err
-		}
-		posts = append(posts, post)
+	t, err := getTemplate(filepath.Join(g.siteInfo.ThemeFolder, "template.html"), urls)
+	if err != nil {
+		return 0, err
+	}
+
+	concurrency := g.concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	read := time.Now()
+	posts, err := g.loadPosts(ctx, concurrency)
+	if err != nil {
+		return 0, err
+	}
+	g.out.read = time.Since(read)
+	posts = g.publishedPosts(posts, time.Now())
+	err = checkLinks(posts)
+	if err != nil {
+		return 0, err
 	}
 	sort.Sort(byDateDesc(posts))
+	return len(posts), runTasks(ctx, g.createTasks(posts, t, urls), concurrency, g.cache)
+}
 
-	generators := g.createTasks(posts, t)
-	err = g.runTasks(generators)
+// loadPosts reads the posts of all the sources, concurrency of them at
+// a time. The posts keep the order of the sources. All the posts that
+// cannot be read are reported in a *BuildError.
+func (g *SiteGenerator) loadPosts(ctx context.Context, concurrency int) ([]*post, error) {
+	var paths []string
+	var sources []Source
+	for _, source := range g.sources {
+		for _, path := range source.Paths {
+			paths = append(paths, path)
+			sources = append(sources, source)
+		}
+	}
+
+	read := make([]*post, len(paths))
+	errs := make([]error, len(paths))
+	tasks := make([]task, len(paths))
+	for i, path := range paths {
+		i, path := i, path
+		tasks[i] = task{name: "post " + path, generator: generatorFunc(func() error {
+			read[i], errs[i] = g.readPost(path)
+			return nil
+		})}
+	}
+	err := runTasks(ctx, tasks, concurrency, nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var failed []*TaskError
+	for i, err := range errs {
+		if err != nil && err != errNoFrontMatter {
+			failed = append(failed, &TaskError{Task: tasks[i].name, Err: err})
+		}
+	}
+	if len(failed) > 0 {
+		return nil, &BuildError{Errors: failed}
+	}
+
+	posts := make([]*post, 0, len(paths))
+	for i, post := range read {
+		path, source := paths[i], sources[i]
+		if errs[i] == errNoFrontMatter {
+			g.out.warnf("Skipping file without front matter: %s...", path)
+			continue
+		}
+		post.section = strings.Trim(source.Prefix, "/")
+		post.meta.Tags = appendMissing(post.meta.Tags, source.Tags)
+		post.meta.Categories = appendMissing(post.meta.Categories, source.Categories)
+		post.path = path
+		post.link = postLink(g.siteInfo, post)
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// checkLinks makes sure that no two posts have the same permalink, since
+// they would be generated in the same file. Only the published posts are
+// checked, so a hidden draft never fails a build.
+func checkLinks(posts []*post) error {
+	links := make(map[string]string)
+	for _, post := range posts {
+		if other, ok := links[post.link]; ok {
+			return fmt.Errorf("posts %s and %s have the same permalink '%s'", other, post.path, post.link)
+		}
+		links[post.link] = post.path
 	}
-	fmt.Println("Finished generating Site...")
 	return nil
 }
 
-func (g *siteGenerator) newPost(path string) (p *post, err error) {
-	meta, err := g.getPostMeta(path)
+// appendMissing adds to list the values it does not already contain,
+// ignoring case.
+func appendMissing(list, values []string) []string {
+	for _, v := range values {
+		found := false
+		for _, l := range list {
+			if strings.EqualFold(l, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// readPost creates the post of path. A post whose files did not change
+// since the previous build is not rendered again.
+func (g *SiteGenerator) readPost(path string) (p *post, err error) {
+	hash, err := hashFiles(path)
 	if err != nil {
 		return nil, err
 	}
-	html, err := getHTML(path)
+	if cached := g.cache.cachedPost(path, hash); cached != nil {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error accessing %s: %v", path, err)
+		}
+		p = &post{meta: copyMeta(cached.Meta), html: []byte(cached.HTML)}
+		if !info.IsDir() {
+			p.name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		} else {
+			p.name = filepath.Base(path)
+			p.dir = path
+			p.imagesDir, p.images, err = getImages(path)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		p, err = g.newPost(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.hash = hash
+	g.cache.storePost(path, &cachedPost{Hash: hash, Meta: copyMeta(p.meta), HTML: string(p.html)})
+	return p, nil
+}
+
+// copyMeta returns a copy of meta that does not share its lists
+func copyMeta(meta *Meta) *Meta {
+	c := *meta
+	c.Tags = append([]string(nil), meta.Tags...)
+	c.Categories = append([]string(nil), meta.Categories...)
+	return &c
+}
+
+// errNoFrontMatter is returned for markdown files that are not posts
+var errNoFrontMatter = errors.New("markdown file has no front matter")
+
+// newPost creates a post from a folder with a post.md, or from
+// a single markdown file with front matter.
+func (g *SiteGenerator) newPost(path string) (p *post, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error accessing %s: %v", path, err)
+	}
+	if !info.IsDir() {
+		return g.newFilePost(path)
+	}
+
+	filePath := filepath.Join(path, "post.md")
+	input, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading file %s: %v", filePath, err)
+	}
+	delimiter, frontMatter, body, err := splitFrontMatter(input)
+	if err != nil {
+		return nil, fmt.Errorf("error reading front matter in %s: %v", filePath, err)
+	}
+	meta, err := g.getPostMeta(filepath.Join(path, "meta.yml"), filePath, delimiter, frontMatter)
+	if err != nil {
+		return nil, err
+	}
+	html, err := getHTML(filePath, body)
 	if err != nil {
 		return nil, err
 	}
@@ -84,145 +319,223 @@ func (g *siteGenerator) newPost(path string) (p *post, err error) {
 	if err != nil {
 		return nil, err
 	}
-	name := path[strings.LastIndex(path, fs.GetSeparator())+1:]
-	p = &post{name: name, meta: meta, html: html, imagesDir: imagesDir, images: images}
+	name := filepath.Base(path)
+	p = &post{name: name, dir: path, meta: meta, html: html, imagesDir: imagesDir, images: images}
 	return p, nil
 }
 
-func (g *siteGenerator) getPostMeta(path string) (*Meta, error) {
-	filePath := filepath.Join(path, "meta.yml")
-	b, err := ioutil.ReadFile(filePath)
+// newFilePost creates a post from a single markdown file. The meta data
+// of the post are in its front matter and its name is the file name.
+func (g *SiteGenerator) newFilePost(filePath string) (p *post, err error) {
+	input, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("error while reading file %s: %v", filePath, err)
 	}
-	meta := Meta{}
-	err = yaml.Unmarshal(b, &meta)
+	delimiter, frontMatter, body, err := splitFrontMatter(input)
 	if err != nil {
-		return nil, fmt.Errorf("error reading yml in %s: %v", filePath, err)
+		return nil, fmt.Errorf("error reading front matter in %s: %v", filePath, err)
 	}
-	parsedDate, err := time.Parse(g.SiteInfo.DateFormat, meta.Date)
+	if frontMatter == nil {
+		return nil, errNoFrontMatter
+	}
+	meta, err := g.getPostMeta("", filePath, delimiter, frontMatter)
+	if err != nil {
+		return nil, err
+	}
+	html, err := getHTML(filePath, body)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	p = &post{name: name, meta: meta, html: html}
+	return p, nil
+}
+
+// getPostMeta reads the meta.yml of the post and the front matter of its
+// markdown file. At least one of them must exist. An empty metaPath means
+// that the post has no meta.yml.
+func (g *SiteGenerator) getPostMeta(metaPath, postPath, delimiter string, frontMatter []byte) (*Meta, error) {
+	meta := Meta{}
+	filePath := metaPath
+	if metaPath != "" {
+		b, err := ioutil.ReadFile(metaPath)
+		if err != nil && !(os.IsNotExist(err) && frontMatter != nil) {
+			return nil, fmt.Errorf("error while reading file %s: %v", metaPath, err)
+		}
+		if err == nil {
+			err = yaml.Unmarshal(b, &meta)
+			if err != nil {
+				return nil, fmt.Errorf("error reading yml in %s: %v", metaPath, err)
+			}
+		}
+	}
+	if frontMatter != nil {
+		filePath = postPath
+		fm := Meta{}
+		err := parseFrontMatter(delimiter, frontMatter, &fm)
+		if err != nil {
+			return nil, fmt.Errorf("error in %s: %v", postPath, err)
+		}
+		err = mergeMeta(&meta, &fm)
+		if err != nil {
+			return nil, fmt.Errorf("error in %s: %v", postPath, err)
+		}
+	}
+
+	// all the dates are local, so the same text means the same instant
+	parsedDate, err := time.ParseInLocation(g.siteInfo.DateFormat, meta.Date, time.Local)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing date in %s: %v", filePath, err)
 	}
 	meta.ParsedDate = parsedDate
+	if meta.PublishDate != "" {
+		meta.ParsedPublishDate, err = time.ParseInLocation(g.siteInfo.DateFormat, meta.PublishDate, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing publish date in %s: %v", filePath, err)
+		}
+	}
+	if meta.ExpiryDate != "" {
+		meta.ParsedExpiryDate, err = time.ParseInLocation(g.siteInfo.DateFormat, meta.ExpiryDate, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing expiry date in %s: %v", filePath, err)
+		}
+	}
 	return &meta, nil
 }
 
-func (g *siteGenerator) createTasks(posts []*post, t *template.Template) []Generator {
-	generators := make([]Generator, 0)
-	destination := g.SiteInfo.DestFolder
+// publishedPosts removes the drafts, the scheduled and the expired posts,
+// unless the generator is set to include them.
+func (g *SiteGenerator) publishedPosts(posts []*post, now time.Time) []*post {
+	published := make([]*post, 0, len(posts))
+	for _, p := range posts {
+		meta := p.meta
+		if meta.Draft && !g.includeDrafts {
+			g.out.infof("Skipping draft post: %s...", meta.Title)
+			continue
+		}
+		if !meta.ParsedPublishDate.IsZero() && meta.ParsedPublishDate.After(now) && !g.includeFuture {
+			g.out.infof("Skipping scheduled post: %s...", meta.Title)
+			continue
+		}
+		if !meta.ParsedExpiryDate.IsZero() && !meta.ParsedExpiryDate.After(now) && !g.includeExpired {
+			g.out.infof("Skipping expired post: %s...", meta.Title)
+			continue
+		}
+		published = append(published, p)
+	}
+	return published
+}
+
+// createTasks returns the generators of every page of the site
+func (g *SiteGenerator) createTasks(posts []*post, t *template.Template, urls *url.Builder) []task {
+	tasks := make([]task, 0)
+	out := g.out
 
 	//posts
 	for _, post := range posts {
-		pg := postGenerator{post, g.SiteInfo, t, destination}
-		generators = append(generators, &pg)
+		pg := postGenerator{post, g.siteInfo, t, urls, out}
+		tasks = append(tasks, task{
+			name:      fmt.Sprintf("post %q (%s)", post.meta.Title, post.link),
+			generator: &pg,
+			hash:      post.hash,
+			outputs:   postOutputs(post.link, post.hasAssets()),
+			page:      Page{URL: post.link, Title: post.meta.Title},
+		})
 	}
 	tagPostsMap := createTagPostsMap(posts)
 
 	// frontpage
-	paging := g.SiteInfo.NumPostsFrontPage
+	paging := g.siteInfo.NumPostsFrontPage
 	numOfPages := getNumberOfPages(posts, paging)
 	for i := 0; i < numOfPages; i++ {
-		to := destination
+		link := "/"
 		if i != 0 {
-			to = filepath.Join(destination, fmt.Sprintf("%d", i+1))
+			link = fmt.Sprintf("/%d/", i+1)
 		}
 		toP := (i + 1) * paging
 		if (i + 1) == numOfPages {
 			toP = len(posts)
 		}
-		lg := &listingGenerator{posts[i*paging : toP], t, g.SiteInfo, to, "", i + 1, numOfPages}
-		generators = append(generators, lg)
+		lg := &listingGenerator{posts[i*paging : toP], t, g.siteInfo, urls, out, link, "", i + 1, numOfPages}
+		tasks = append(tasks, listingTask(lg))
 	}
 
 	// archive
-	ag := listingGenerator{posts, t, g.SiteInfo, filepath.Join(destination, "archive"), "Archive", 0, 0}
+	ag := listingGenerator{posts, t, g.siteInfo, urls, out, "/archive/", "Archive", 0, 0}
+	tasks = append(tasks, listingTask(&ag))
 	// tags
 	tg := tagsGenerator{
 		tagPostsMap: tagPostsMap,
 		template:    t,
-		siteInfo:    g.SiteInfo,
+		siteInfo:    g.siteInfo,
+		urls:        urls,
+		out:         out,
+	}
+	tasks = append(tasks, indexTask(getTagIndexLink(g.siteInfo), "Tags", &tg, tagPostsMap))
+	for tag, tagPosts := range tagPostsMap {
+		lg := &listingGenerator{tagPosts, t, g.siteInfo, urls, out, getTagLink(g.siteInfo, tag), tag, 0, 0}
+		tasks = append(tasks, listingTask(lg))
 	}
 	// categories
 	catPostsMap := createCatPostsMap(posts)
 	ct := categoriesGenerator{
 		catPostsMap: catPostsMap,
 		template:    t,
-		destination: destination,
-		siteInfo:    g.SiteInfo,
+		siteInfo:    g.siteInfo,
+		urls:        urls,
+		out:         out,
+	}
+	tasks = append(tasks, indexTask(getCatIndexLink(g.siteInfo), "Categories", &ct, catPostsMap))
+	for cat, catPosts := range catPostsMap {
+		lg := &listingGenerator{catPosts, t, g.siteInfo, urls, out, getCatLink(g.siteInfo, cat), cat, 0, 0}
+		tasks = append(tasks, listingTask(lg))
 	}
 	// sitemap
 	sg := sitemapGenerator{
 		posts:            posts,
 		tagPostsMap:      tagPostsMap,
 		categoryPostsMap: catPostsMap,
-		destination:      destination,
-		blogURL:          g.SiteInfo.BlogURL,
+		siteInfo:         g.siteInfo,
+		urls:             urls,
+		out:              out,
 	}
 	// rss
 	rg := rssGenerator{
-		posts:       posts,
-		destination: destination,
-		siteInfo:    g.SiteInfo,
+		posts:    posts,
+		siteInfo: g.siteInfo,
+		urls:     urls,
+		out:      out,
 	}
 	// statics
 	fileToDestination := make(map[string]string)
 	templateToFile := make(map[string]string)
-	for _, row := range g.SiteInfo.StaticPages {
+	for _, row := range g.siteInfo.StaticPages {
+		file := filepath.Join(g.siteInfo.ThemeFolder, row.File)
 		if row.IsTemplate {
-			templateToFile[filepath.Join(g.SiteInfo.ThemeFolder, row.File)] = filepath.Join(destination, row.To)
+			templateToFile[file] = staticLink(row.To)
 			continue
 		}
-		fileToDestination[filepath.Join(g.SiteInfo.ThemeFolder, row.File)] = filepath.Join(destination, row.To)
+		// the file keeps its name in the folder of To
+		fileToDestination[file] = path.Join(path.Dir(filepath.ToSlash(row.To)), filepath.Base(row.File))
 	}
 	statg := staticsGenerator{
 		fileToDestination: fileToDestination,
 		templateToFile:    templateToFile,
 		template:          t,
-		siteInfo:          g.SiteInfo,
+		siteInfo:          g.siteInfo,
+		urls:              urls,
+		out:               out,
 	}
-	generators = append(generators, &ag, &tg, &ct, &sg, &rg, &statg)
-	return generators
-}
-
-func (g *siteGenerator) runTasks(generators []Generator) (err error) {
-	var wg sync.WaitGroup
-	finished := make(chan bool, 1)
-	errors := make(chan error, 1)
-	pool := make(chan struct{}, 50)
-
-	for _, generator := range generators {
-		wg.Add(1)
-		go func(g Generator) {
-			defer wg.Done()
-			pool <- struct{}{}
-			defer func() { <-pool }()
-
-			err := g.Generate()
-			if err != nil {
-				errors <- err
-			}
-		}(generator)
-	}
-
-	go func() {
-		wg.Wait()
-		close(finished)
-	}()
-
-	select {
-	case <-finished:
-		return nil
-	case err := <-errors:
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return append(tasks,
+		task{name: "sitemap", generator: &sg, hash: sitemapHash(posts, tagPostsMap, catPostsMap), outputs: []string{"sitemap.xml"}},
+		task{name: "rss", generator: &rg, hash: postsHash(posts), outputs: []string{"index.xml"}},
+		task{name: "static pages", generator: &statg},
+	)
 }
 
 type htmlConfig struct {
-	path       string
+	// link is the site relative url of the page
+	link       string
 	pageTitle  string
 	pageNum    int
 	maxPageNum int
@@ -230,45 +543,49 @@ type htmlConfig struct {
 	temp       *template.Template
 	content    template.HTML
 	siteInfo   *config.SiteInformation
+	urls       *url.Builder
+	out        *output
 }
 
 func (h htmlConfig) writeHTML() error {
-	filePath := filepath.Join(h.path, "index.html")
-	f, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("error creating file %s: %v", filePath, err)
-	}
-	defer f.Close()
-	w := bufio.NewWriter(f)
 	next := h.pageNum + 1
 	prev := h.pageNum - 1
 	if h.pageNum == h.maxPageNum {
 		next = 0
 	}
 
-	u := url.ChangePathToUrl(h.path)
+	link := h.urls.AbsURL(h.link)
 	td := IndexData{
 		Name:          h.siteInfo.Author,
 		Year:          time.Now().Year(),
 		HTMLTitle:     getHTMLTitle(h.pageTitle, h.siteInfo.BlogTitle),
 		PageTitle:     h.pageTitle,
 		Content:       h.content,
-		CanonicalLink: buildCanonicalLink(u, h.siteInfo.BlogURL),
+		CanonicalLink: link,
 		PageNum:       h.pageNum,
 		NextPageNum:   next,
 		PrevPageNum:   prev,
-		URL:           buildCanonicalLink(u, h.siteInfo.BlogURL),
+		URL:           link,
 		IsPost:        h.isPost,
 	}
 
-	err = h.temp.Execute(w, td)
-	if err != nil {
-		return fmt.Errorf("error executing template %s: %v", templatePath, err)
-	}
-	err = w.Flush()
+	name := pageFile(h.link)
+	err := h.out.writeFile(name, func(f io.Writer) error {
+		w := bufio.NewWriter(f)
+		err := h.temp.Execute(w, td)
+		if err != nil {
+			return fmt.Errorf("error executing template %s: %v", h.temp.Name(), err)
+		}
+		err = w.Flush()
+		if err != nil {
+			return fmt.Errorf("error writing file %s: %v", name, err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("error writing file %s: %v", filePath, err)
+		return err
 	}
+	h.out.addPage(Page{URL: h.link, Title: h.pageTitle})
 	return nil
 }
 
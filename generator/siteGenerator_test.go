@@ -0,0 +1,148 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RomanosTrechlis/blog-gen/config"
+)
+
+func TestAppendMissing(t *testing.T) {
+	var tests = []struct {
+		list   []string
+		values []string
+		result []string
+	}{
+		{nil, nil, nil},
+		{[]string{"go"}, nil, []string{"go"}},
+		{nil, []string{"go"}, []string{"go"}},
+		{[]string{"Go", "web"}, []string{"go", "notes"}, []string{"Go", "web", "notes"}},
+	}
+
+	for _, tt := range tests {
+		result := appendMissing(tt.list, tt.values)
+		if !reflect.DeepEqual(result, tt.result) {
+			t.Errorf("expected %v, got %v", tt.result, result)
+		}
+	}
+}
+
+func TestLoadPosts(t *testing.T) {
+	root, err := ioutil.TempDir("", "blog-gen-posts")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	var paths []string
+	for i := 0; i < 20; i++ {
+		path := filepath.Join(root, fmt.Sprintf("post-%02d.md", i))
+		writeFile(t, path, fmt.Sprintf("---\ntitle: Post %d\ndate: 2020-01-02\n---\n# Post", i))
+		paths = append(paths, path)
+	}
+	siteInfo := config.SiteInformation{
+		DateFormat: "2006-01-02",
+		Permalinks: config.Permalinks{Post: "/:slug/"},
+	}
+	g, err := New(WithConfig(siteInfo), WithSources(Source{Paths: paths}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	posts, err := g.loadPosts(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for i, p := range posts {
+		if p.meta.Title != fmt.Sprintf("Post %d", i) {
+			t.Errorf("expected posts in the order of their source, got %s at %d", p.meta.Title, i)
+		}
+	}
+
+	writeFile(t, paths[3], "---\ntitle: Broken\ndate: yesterday\n---\n")
+	writeFile(t, paths[11], "---\ntitle: [broken\n---\n")
+	_, err = g.loadPosts(context.Background(), 4)
+	berr, ok := err.(*BuildError)
+	if !ok {
+		t.Fatalf("expected a build error, got %v", err)
+	}
+	if len(berr.Errors) != 2 || berr.Errors[0].Task != "post "+paths[3] || berr.Errors[1].Task != "post "+paths[11] {
+		t.Errorf("expected the two broken posts in order, got %v", berr)
+	}
+}
+
+func TestPublishedPosts(t *testing.T) {
+	now := time.Date(2020, time.June, 1, 12, 0, 0, 0, time.Local)
+	past := now.Add(-24 * time.Hour)
+	future := now.Add(24 * time.Hour)
+	posts := []*post{
+		{name: "plain", meta: &Meta{}},
+		{name: "draft", meta: &Meta{Draft: true}},
+		{name: "scheduled", meta: &Meta{ParsedPublishDate: future}},
+		{name: "published", meta: &Meta{ParsedPublishDate: past}},
+		{name: "expired", meta: &Meta{ParsedExpiryDate: past}},
+		{name: "expiring", meta: &Meta{ParsedExpiryDate: future}},
+		{name: "now", meta: &Meta{ParsedPublishDate: now, ParsedExpiryDate: now}},
+	}
+
+	tests := []struct {
+		drafts, future, expired bool
+		names                   string
+	}{
+		{false, false, false, "plain published expiring"},
+		{true, false, false, "plain draft published expiring"},
+		{false, true, false, "plain scheduled published expiring"},
+		{false, false, true, "plain published expired expiring now"},
+		{true, true, true, "plain draft scheduled published expired expiring now"},
+	}
+
+	for _, tt := range tests {
+		g := &SiteGenerator{includeDrafts: tt.drafts, includeFuture: tt.future, includeExpired: tt.expired}
+		var names []string
+		for _, p := range g.publishedPosts(posts, now) {
+			names = append(names, p.name)
+		}
+		if strings.Join(names, " ") != tt.names {
+			t.Errorf("drafts %v, future %v, expired %v: expected %s, got %v",
+				tt.drafts, tt.future, tt.expired, tt.names, names)
+		}
+	}
+}
+
+func TestCheckLinks(t *testing.T) {
+	now := time.Date(2020, time.June, 1, 12, 0, 0, 0, time.Local)
+	posts := []*post{
+		{path: "a.md", link: "/a/", meta: &Meta{}},
+		{path: "draft.md", link: "/a/", meta: &Meta{Draft: true}},
+	}
+	g := &SiteGenerator{}
+	err := checkLinks(g.publishedPosts(posts, now))
+	if err != nil {
+		t.Errorf("expected no error for a hidden draft, got %v", err)
+	}
+	g.includeDrafts = true
+	err = checkLinks(g.publishedPosts(posts, now))
+	if err == nil {
+		t.Errorf("expected an error for two published posts with the same permalink")
+	}
+}
+
+func TestGetPostMetaDates(t *testing.T) {
+	g := &SiteGenerator{siteInfo: &config.SiteInformation{DateFormat: "2006-01-02 15:04"}}
+	fm := []byte("date: 2020-01-02 10:00\npublishdate: 2020-01-02 10:00\nexpirydate: 2020-01-02 10:00\n")
+	meta, err := g.getPostMeta("", "post.md", "---", fm)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !meta.ParsedDate.Equal(meta.ParsedPublishDate) || !meta.ParsedDate.Equal(meta.ParsedExpiryDate) {
+		t.Errorf("expected the same instant for the same date, got %v, %v and %v",
+			meta.ParsedDate, meta.ParsedPublishDate, meta.ParsedExpiryDate)
+	}
+}
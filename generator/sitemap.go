@@ -2,9 +2,10 @@ package generator
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
 
+	"github.com/RomanosTrechlis/blog-gen/config"
+	"github.com/RomanosTrechlis/blog-gen/util/url"
 	"github.com/beevik/etree"
 )
 
@@ -13,13 +14,14 @@ type sitemapGenerator struct {
 	posts            []*post
 	tagPostsMap      map[string][]*post
 	categoryPostsMap map[string][]*post
-	destination      string
-	blogURL          string
+	siteInfo         *config.SiteInformation
+	urls             *url.Builder
+	out              *output
 }
 
 // Generate creates the sitemap
 func (g *sitemapGenerator) Generate() (err error) {
-	fmt.Println("\tGenerating Sitemap...")
+	g.out.debugf("Generating Sitemap...")
 	doc := etree.NewDocument()
 	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
 	urlSet := doc.CreateElement("urlset")
@@ -28,49 +30,47 @@ func (g *sitemapGenerator) Generate() (err error) {
 
 	url := urlSet.CreateElement("url")
 	loc := url.CreateElement("loc")
-	loc.SetText(g.blogURL)
+	loc.SetText(g.urls.AbsURL("/"))
 
-	g.addURL(urlSet, "about", nil)
-	g.addURL(urlSet, "archive", nil)
-	g.addURL(urlSet, "tags", nil)
-	g.addURL(urlSet, "categories", nil)
+	g.addURL(urlSet, "/about/", nil)
+	g.addURL(urlSet, "/archive/", nil)
+	g.addURL(urlSet, getTagIndexLink(g.siteInfo), nil)
+	g.addURL(urlSet, getCatIndexLink(g.siteInfo), nil)
 
 	for tag := range g.tagPostsMap {
-		g.addURL(urlSet, tag, nil)
+		g.addURL(urlSet, getTagLink(g.siteInfo, tag), nil)
 	}
 
 	for cat := range g.categoryPostsMap {
-		g.addURL(urlSet, cat, nil)
+		g.addURL(urlSet, getCatLink(g.siteInfo, cat), nil)
 	}
 
 	for _, post := range g.posts {
-		g.addURL(urlSet, post.name[1:], post.images)
+		g.addURL(urlSet, post.link, post.images)
 	}
 
-	filePath := filepath.Join(g.destination, "sitemap.xml")
-	f, err := os.Create(filePath)
+	err = g.out.writeFile("sitemap.xml", func(w io.Writer) error {
+		_, err := doc.WriteTo(w)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("error creating file %s: %v", filePath, err)
+		return fmt.Errorf("error writing to file sitemap.xml: %v", err)
 	}
-	f.Close()
-	err = doc.WriteToFile(filePath)
-	if err != nil {
-		return fmt.Errorf("error writing to file %s: %v", filePath, err)
-	}
-	fmt.Println("\tFinished generating Sitemap...")
+	g.out.debugf("Finished generating Sitemap...")
 	return nil
 }
 
-func (g *sitemapGenerator) addURL(element *etree.Element, location string, images []string) {
+// addURL adds the page of the site relative link and its images
+func (g *sitemapGenerator) addURL(element *etree.Element, link string, images []string) {
 	url := element.CreateElement("url")
 	loc := url.CreateElement("loc")
-	loc.SetText(fmt.Sprintf("%s/%s/", g.blogURL, location))
+	loc.SetText(g.urls.AbsURL(link))
 
 	if len(images) > 0 {
 		for _, image := range images {
 			img := url.CreateElement("image:image")
 			imgLoc := img.CreateElement("image:loc")
-			imgLoc.SetText(fmt.Sprintf("%s/%s/images/%s", g.blogURL, location, image))
+			imgLoc.SetText(g.urls.AbsURL(assetLink(link) + "images/" + image))
 		}
 	}
 }
@@ -13,15 +13,20 @@ import (
 
 // staticsGenerator object
 type staticsGenerator struct {
+	// fileToDestination maps the files to the files of the blog they
+	// are copied to, relative to its root
 	fileToDestination map[string]string
-	templateToFile    map[string]string
-	template          *template.Template
-	siteInfo          *config.SiteInformation
+	// templateToFile maps the templates to the links of their pages
+	templateToFile map[string]string
+	template       *template.Template
+	siteInfo       *config.SiteInformation
+	urls           *url.Builder
+	out            *output
 }
 
 // Generate creates the static pages
 func (g *staticsGenerator) Generate() error {
-	fmt.Println("\tCopying Statics...")
+	g.out.debugf("Copying Statics...")
 
 	err := g.resolveFileToDestination()
 	if err != nil {
@@ -33,7 +38,7 @@ func (g *staticsGenerator) Generate() error {
 		return err
 	}
 
-	fmt.Println("\tFinished copying statics...")
+	g.out.debugf("Finished copying statics...")
 	return nil
 }
 
@@ -43,15 +48,7 @@ func (g *staticsGenerator) resolveFileToDestination() error {
 	}
 
 	for k, v := range g.fileToDestination {
-		folder := fs.GetFolderNameFrom(v)
-		if folder != "" {
-			err := fs.CreateFolderIfNotExist(folder)
-			if err != nil {
-				return err
-			}
-		}
-
-		err := fs.CopyFile(k, fs.GetFolderNameFrom(v))
+		err := g.out.copyFile(k, v)
 		if err != nil {
 			return err
 		}
@@ -63,22 +60,14 @@ func (g *staticsGenerator) resolveTemplateToFile() error {
 	if len(g.templateToFile) == 0 {
 		return nil
 	}
-	for k, v := range g.templateToFile {
-		folder := fs.GetFolderNameFrom(v)
-		if folder != "" {
-			err := fs.CreateFolderIfNotExist(folder)
-			if err != nil {
-				return err
-			}
-		}
-
+	for k, link := range g.templateToFile {
 		content, err := ioutil.ReadFile(k)
 		if err != nil {
 			return fmt.Errorf("error reading file %s: %v", k, err)
 		}
 
 		c := htmlConfig{
-			path:       url.ChangePathToUrl(folder),
+			link:       link,
 			pageTitle:  getTitle(k),
 			pageNum:    0,
 			maxPageNum: 0,
@@ -86,6 +75,8 @@ func (g *staticsGenerator) resolveTemplateToFile() error {
 			temp:       g.template,
 			content:    template.HTML(content),
 			siteInfo:   g.siteInfo,
+			urls:       g.urls,
+			out:        g.out,
 		}
 		err = c.writeHTML()
 		if err != nil {
@@ -8,6 +8,7 @@ import (
 	"sort"
 
 	"github.com/RomanosTrechlis/blog-gen/config"
+	"github.com/RomanosTrechlis/blog-gen/util/url"
 )
 
 // Tag holds the data for a Tag
@@ -25,43 +26,30 @@ type tagsGenerator struct {
 	tagPostsMap map[string][]*post
 	template    *template.Template
 	siteInfo    *config.SiteInformation
+	urls        *url.Builder
+	out         *output
 }
 
-// Generate creates the tags page
+// Generate creates the tags page. The page of every tag is a listing.
 func (g *tagsGenerator) Generate() (err error) {
-	fmt.Println("\tGenerating Tags...")
-	siteInfo := g.siteInfo
-
-	tagPostsMap := g.tagPostsMap
-	tagsPath := filepath.Join(siteInfo.DestFolder, "tags")
-	err = clearAndCreateDestination(tagsPath)
-	if err != nil {
-		return err
-	}
+	g.out.debugf("Generating Tags...")
 	err = g.generateTagIndex()
 	if err != nil {
 		return err
 	}
-	for tag, tagPosts := range tagPostsMap {
-		err := g.generateTagPage(tag, tagPosts)
-		if err != nil {
-			return err
-		}
-	}
-	fmt.Println("\tFinished generating Tags...")
+	g.out.debugf("Finished generating Tags...")
 	return nil
 }
 
 func (g *tagsGenerator) generateTagIndex() (err error) {
-	tagsPath := filepath.Join(g.siteInfo.DestFolder, "tags")
 	tagsTemplatePath := filepath.Join(g.siteInfo.ThemeFolder, "tags.html")
-	tmpl, err := getTemplate(tagsTemplatePath)
+	tmpl, err := getTemplate(tagsTemplatePath, g.urls)
 	if err != nil {
 		return err
 	}
 	tags := make([]*Tag, 0)
 	for tag, posts := range g.tagPostsMap {
-		tags = append(tags, &Tag{Name: tag, Link: getTagLink(tag), Count: len(posts)})
+		tags = append(tags, &Tag{Name: tag, Link: g.urls.RelURL(getTagLink(g.siteInfo, tag)), Count: len(posts)})
 	}
 	sort.Sort(byCountDesc(tags))
 	buf := bytes.Buffer{}
@@ -71,7 +59,7 @@ func (g *tagsGenerator) generateTagIndex() (err error) {
 	}
 
 	c := htmlConfig{
-		path:       tagsPath,
+		link:       getTagIndexLink(g.siteInfo),
 		pageTitle:  "Tags",
 		pageNum:    0,
 		maxPageNum: 0,
@@ -79,6 +67,8 @@ func (g *tagsGenerator) generateTagIndex() (err error) {
 		temp:       g.template,
 		content:    template.HTML(buf.String()),
 		siteInfo:   g.siteInfo,
+		urls:       g.urls,
+		out:        g.out,
 	}
 	err = c.writeHTML()
 	if err != nil {
@@ -87,27 +77,6 @@ func (g *tagsGenerator) generateTagIndex() (err error) {
 	return nil
 }
 
-func (g *tagsGenerator) generateTagPage(tag string, posts []*post) (err error) {
-	tagPagePath := filepath.Join(g.siteInfo.DestFolder, "tags", tag)
-	err = clearAndCreateDestination(tagPagePath)
-	if err != nil {
-		return err
-	}
-	lg := listingGenerator{
-		posts:       posts,
-		template:    g.template,
-		pageTitle:   tag,
-		siteInfo:    g.siteInfo,
-		destination: tagPagePath,
-	}
-
-	err = lg.Generate()
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 func (t byCountDesc) Len() int {
 	return len(t)
 }
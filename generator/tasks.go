@@ -0,0 +1,109 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// task is one of the generators of the site, named after the post or
+// the page it creates
+type task struct {
+	name      string
+	generator Generator
+	// hash is the hash of the inputs of the task and outputs are the
+	// files and folders it creates, relative to the destination folder.
+	// Tasks without a hash are always run.
+	hash    string
+	outputs []string
+	// page is the html page of the task, if it creates one
+	page Page
+}
+
+// generatorFunc turns a function into a Generator
+type generatorFunc func() error
+
+// Generate calls f
+func (f generatorFunc) Generate() error {
+	return f()
+}
+
+// TaskError is the failure of one of the tasks of a build
+type TaskError struct {
+	Task string
+	Err  error
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Task, e.Err)
+}
+
+// BuildError holds the failures of all the tasks of a build
+type BuildError struct {
+	Errors []*TaskError
+}
+
+func (e *BuildError) Error() string {
+	problems := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		problems = append(problems, err.Error())
+	}
+	return fmt.Sprintf("tasks failed:\n\t%s", strings.Join(problems, "\n\t"))
+}
+
+// runTasks runs the tasks, at most concurrency of them at a time. The
+// first failure cancels the tasks that have not started yet, while the
+// running ones are left to finish, so that no file is half written.
+// Tasks whose outputs are reused from cache are not run. It returns a
+// *BuildError with every failure, or the error of ctx when the build is
+// canceled.
+func runTasks(ctx context.Context, tasks []task, concurrency int, cache *buildCache) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var failed []*TaskError
+	var wg sync.WaitGroup
+	queue := make(chan task)
+	for i := 0; i < concurrency && i < len(tasks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range queue {
+				if ctx.Err() != nil || cache.reuse(t) {
+					continue
+				}
+				err := t.generator.Generate()
+				if err != nil {
+					mu.Lock()
+					failed = append(failed, &TaskError{Task: t.name, Err: err})
+					mu.Unlock()
+					cancel()
+					continue
+				}
+				cache.store(t)
+			}
+		}()
+	}
+
+	for _, t := range tasks {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case queue <- t:
+		case <-ctx.Done():
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return &BuildError{Errors: failed}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunTasks(t *testing.T) {
+	var count int32
+	tasks := make([]task, 0)
+	for i := 0; i < 20; i++ {
+		tasks = append(tasks, task{name: "page", generator: generatorFunc(func() error {
+			atomic.AddInt32(&count, 1)
+			return nil
+		})})
+	}
+	err := runTasks(context.Background(), tasks, 4, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if count != 20 {
+		t.Errorf("expected 20 tasks to run, got %d", count)
+	}
+}
+
+func TestRunTasksErrors(t *testing.T) {
+	var count int32
+	fail := generatorFunc(func() error {
+		atomic.AddInt32(&count, 1)
+		return errors.New("broken")
+	})
+	tasks := []task{{name: "post a", generator: fail}, {name: "post b", generator: fail}, {name: "post c", generator: fail}}
+
+	// with one worker the first failure cancels the remaining tasks
+	err := runTasks(context.Background(), tasks, 1, nil)
+	berr, ok := err.(*BuildError)
+	if !ok {
+		t.Fatalf("expected a build error, got %v", err)
+	}
+	if len(berr.Errors) != 1 || berr.Errors[0].Task != "post a" || count != 1 {
+		t.Errorf("expected only post a to fail, got %v", berr)
+	}
+
+	// tasks that already run report their failures too
+	count = 0
+	err = runTasks(context.Background(), tasks, 3, nil)
+	berr, ok = err.(*BuildError)
+	if !ok {
+		t.Fatalf("expected a build error, got %v", err)
+	}
+	if len(berr.Errors) != int(count) {
+		t.Errorf("expected %d failures, got %v", count, berr)
+	}
+}
+
+func TestRunTasksCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ran := false
+	tasks := []task{{name: "page /", generator: generatorFunc(func() error {
+		ran = true
+		return nil
+	})}}
+	err := runTasks(ctx, tasks, 2, nil)
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if ran {
+		t.Errorf("expected no task to run")
+	}
+}
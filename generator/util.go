@@ -3,39 +3,21 @@ package generator
 import (
 	"fmt"
 	"html/template"
-	"os"
-	"strings"
+	"path/filepath"
 
-	"github.com/RomanosTrechlis/blog-gen/util/fs"
+	"github.com/RomanosTrechlis/blog-gen/util/url"
 )
 
-func clearAndCreateDestination(path string) (err error) {
-	err = fs.ClearFolder(path)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			return fmt.Errorf("error removing folder at destination %s: %v ", path, err)
-		}
+// getTemplate parses the template of path with the absURL and relURL
+// functions, which create the urls of the site from its relative links
+func getTemplate(path string, urls *url.Builder) (t *template.Template, err error) {
+	funcs := template.FuncMap{
+		"absURL": urls.AbsURL,
+		"relURL": urls.RelURL,
 	}
-	return fs.CreateFolderIfNotExist(path)
-}
-
-func getTemplate(path string) (t *template.Template, err error) {
-	t, err = template.ParseFiles(path)
+	t, err = template.New(filepath.Base(path)).Funcs(funcs).ParseFiles(path)
 	if err != nil {
 		return nil, fmt.Errorf("error reading template %s: %v", path, err)
 	}
 	return t, nil
 }
-
-func buildCanonicalLink(path, baseURL string) (link string) {
-	parts := strings.Split(path, "/")
-	if len(parts) > 2 {
-		return fmt.Sprintf("%s/%s/index.html", baseURL, strings.Join(parts[2:], "/"))
-	}
-	return "/"
-}
-
-func getTagLink(tag string) (link string) {
-	link = fmt.Sprintf("/tags/%s/", strings.ToLower(tag))
-	return link
-}
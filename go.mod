@@ -3,9 +3,11 @@ module github.com/RomanosTrechlis/blog-gen
 go 1.12
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/PuerkitoBio/goquery v1.5.1
 	github.com/RomanosTrechlis/go-icls v0.0.0-20180822074847-595fcc2bff6e
 	github.com/beevik/etree v1.1.0
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/kr/pretty v0.2.0 // indirect
 	github.com/russross/blackfriday v2.0.0+incompatible
 	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
@@ -6,8 +6,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strings"
 	"runtime"
+	"sort"
+	"strings"
 )
 
 func CopyFile(src, dest string) (err error) {
@@ -68,6 +69,64 @@ func GetContentFolders(path string) (result []string, err error) {
 	return result, nil
 }
 
+// GetContent returns the non hidden folders and markdown
+// files inside path. Each one of them is a post.
+func GetContent(path string) (result []string, err error) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error accessing directory %s: %v", path, err)
+	}
+	defer dir.Close()
+	files, err := dir.Readdir(-1)
+	if err != nil {
+		return nil, fmt.Errorf("error reading contents of directory %s: %v", path, err)
+	}
+	for _, file := range files {
+		if file.Name()[0] == '.' {
+			continue
+		}
+		if file.IsDir() || strings.EqualFold(filepath.Ext(file.Name()), ".md") {
+			result = append(result, filepath.Join(path, file.Name()))
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// GetFiles returns the paths, relative to path, of all the files
+// inside path and its subfolders. Hidden files and folders are skipped.
+// A path that is a symlink, e.g. a published build, is followed.
+func GetFiles(path string) (result []string, err error) {
+	root, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading contents of directory %s: %v", path, err)
+	}
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != root && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		result = append(result, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading contents of directory %s: %v", path, err)
+	}
+	return result, nil
+}
+
 func CopyDir(source, dest string) (err error) {
 	files, err := ioutil.ReadDir(source)
 	if err != nil {
@@ -84,7 +143,7 @@ func CopyDir(source, dest string) (err error) {
 			if err != nil {
 				return err
 			}
-			return nil
+			continue
 		}
 
 		dst := filepath.Join(dest, file.Name())
@@ -110,7 +169,7 @@ func CreateFolderIfNotExist(path string) (err error) {
 		return fmt.Errorf("error accessing directory %s: %v", path, err)
 	}
 
-	err = os.Mkdir(path, os.ModePerm)
+	err = os.MkdirAll(path, os.ModePerm)
 	if err != nil {
 		return fmt.Errorf("error creating directory %s: %v", path, err)
 	}
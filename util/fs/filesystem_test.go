@@ -42,9 +42,11 @@ func TestCopyDir(t *testing.T) {
 		desc         string
 		err          bool
 		removeFolder bool
+		num          int
 	}{
-		{filepath.Join("testdata", "existing"), filepath.Join("testdata", "copy"), "", false, true},
-		{filepath.Join("testdata", "nofolder"), filepath.Join("testdata", "copy"), "", true, true},
+		{filepath.Join("testdata", "existing"), filepath.Join("testdata", "copy"), "", false, true, 1},
+		{filepath.Join("testdata", "nofolder"), filepath.Join("testdata", "copy"), "", true, true, 0},
+		{filepath.Join("testdata", "folder2"), filepath.Join("testdata", "copy"), "", false, true, 2},
 	}
 
 	for _, tt := range tests {
@@ -59,6 +61,14 @@ func TestCopyDir(t *testing.T) {
 			continue
 		}
 
+		files, err := fs.GetFiles(tt.to)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if len(files) != tt.num {
+			t.Errorf("expected %d copied files, got %d", tt.num, len(files))
+		}
+
 		if tt.removeFolder {
 			os.RemoveAll(tt.to)
 		}
@@ -165,6 +175,36 @@ func TestGetContentFolders(t *testing.T) {
 	}
 }
 
+func TestGetContent(t *testing.T) {
+	var tests = []struct {
+		folder string
+		desc   string
+		err    bool
+		num    int
+	}{
+		{filepath.Join("testdata", "existing"), "folder has only a text file", false, 0},
+		{filepath.Join("testdata", "notExisting"), "folder doesn't exist", true, 0},
+		{filepath.Join("testdata"), "folder has 3 folders and 1 markdown file", false, 4},
+	}
+
+	for _, tt := range tests {
+		c, err := fs.GetContent(tt.folder)
+		if err != nil && !tt.err {
+			t.Errorf("expected no error, got '%v'", err)
+		}
+		if err == nil && tt.err {
+			t.Error("expected error, got no error")
+		}
+		if err != nil {
+			continue
+		}
+
+		if len(c) != tt.num {
+			t.Errorf("expected %d posts, got %d (%s)", tt.num, len(c), tt.desc)
+		}
+	}
+}
+
 func TestClearFolder(t *testing.T) {
 	var tests = []struct {
 		folder       string
@@ -219,3 +259,54 @@ func TestClearFolder(t *testing.T) {
 		}
 	}
 }
+
+func TestGetFiles(t *testing.T) {
+	var tests = []struct {
+		folder string
+		desc   string
+		err    bool
+		num    int
+	}{
+		{filepath.Join("testdata", "existing"), "folder exists and has 1 file", false, 1},
+		{filepath.Join("testdata", "notExisting"), "folder doesn't exist", true, 0},
+		{filepath.Join("testdata"), "folder has 5 files", false, 5},
+	}
+
+	for _, tt := range tests {
+		files, err := fs.GetFiles(tt.folder)
+		if err != nil && !tt.err {
+			t.Errorf("expected no error, got '%v'", err)
+		}
+		if err == nil && tt.err {
+			t.Error("expected error, got no error")
+		}
+		if err != nil {
+			continue
+		}
+
+		if len(files) != tt.num {
+			t.Errorf("expected %d number of files, got %d (%s)", tt.num, len(files), tt.desc)
+		}
+	}
+}
+
+func TestGetFilesSymlink(t *testing.T) {
+	target, err := filepath.Abs(filepath.Join("testdata", "existing"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	link := filepath.Join("testdata", "link")
+	err = os.Symlink(target, link)
+	if err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+	defer os.Remove(link)
+
+	files, err := fs.GetFiles(link)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected 1 file, got %v", files)
+	}
+}
@@ -0,0 +1,168 @@
+// Package logger contains the leveled logger of the generator, the data
+// sources and the endpoints
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the importance of a message
+type Level int
+
+// The levels of the messages, from the least important
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+func (l Level) String() string {
+	switch l {
+	case Debug:
+		return "debug"
+	case Info:
+		return "info"
+	case Warn:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// Fields are the structured data of a message
+type Fields map[string]interface{}
+
+// Logger receives the messages of the generator, the data sources and
+// the endpoints
+type Logger interface {
+	Log(level Level, msg string, fields Fields)
+}
+
+// The formats of the loggers of New
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// Discard is a Logger that drops every message
+var Discard Logger = discard{}
+
+type discard struct{}
+
+func (discard) Log(level Level, msg string, fields Fields) {}
+
+// New creates a logger that writes the messages of level and above, one
+// per line, in format. Warnings and errors are written to errOut, the
+// rest to out.
+func New(out, errOut io.Writer, level Level, format string) (Logger, error) {
+	if format == "" {
+		format = TextFormat
+	}
+	if format != TextFormat && format != JSONFormat {
+		return nil, fmt.Errorf("unknown log format '%s', use %s or %s", format, TextFormat, JSONFormat)
+	}
+	return &writer{out: out, errOut: errOut, level: level, json: format == JSONFormat}, nil
+}
+
+// writer is the Logger of New
+type writer struct {
+	mu          sync.Mutex
+	out, errOut io.Writer
+	level       Level
+	json        bool
+}
+
+func (w *writer) Log(level Level, msg string, fields Fields) {
+	if level < w.level {
+		return
+	}
+	var line string
+	if w.json {
+		line = jsonLine(level, msg, fields)
+	} else {
+		line = textLine(level, msg, fields)
+	}
+	out := w.out
+	if level >= Warn {
+		out = w.errOut
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprintln(out, line)
+}
+
+// textLine returns msg with its sorted fields as key=value
+func textLine(level Level, msg string, fields Fields) string {
+	if level >= Warn {
+		msg = level.String() + ": " + msg
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{msg}
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, fields[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// jsonLine returns the message as a json object. The time, the level and
+// the message take precedence over fields with the same name.
+func jsonLine(level Level, msg string, fields Fields) string {
+	entry := make(map[string]interface{}, len(fields)+3)
+	for k, v := range fields {
+		switch v := v.(type) {
+		case time.Duration:
+			entry[k] = v.String()
+		case error:
+			entry[k] = v.Error()
+		default:
+			entry[k] = v
+		}
+	}
+	entry["time"] = time.Now().Format(time.RFC3339)
+	entry["level"] = level.String()
+	entry["msg"] = msg
+	b, err := json.Marshal(entry)
+	if err != nil {
+		b, _ = json.Marshal(map[string]string{"level": level.String(), "msg": msg})
+	}
+	return string(b)
+}
+
+// Debugf logs a formatted message at the Debug level. A nil l drops it.
+func Debugf(l Logger, format string, v ...interface{}) {
+	logf(l, Debug, format, v...)
+}
+
+// Infof logs a formatted message at the Info level. A nil l drops it.
+func Infof(l Logger, format string, v ...interface{}) {
+	logf(l, Info, format, v...)
+}
+
+// Warnf logs a formatted message at the Warn level. A nil l drops it.
+func Warnf(l Logger, format string, v ...interface{}) {
+	logf(l, Warn, format, v...)
+}
+
+// Errorf logs a formatted message at the Error level. A nil l drops it.
+func Errorf(l Logger, format string, v ...interface{}) {
+	logf(l, Error, format, v...)
+}
+
+func logf(l Logger, level Level, format string, v ...interface{}) {
+	if l == nil {
+		return
+	}
+	l.Log(level, fmt.Sprintf(format, v...), nil)
+}
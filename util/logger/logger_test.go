@@ -0,0 +1,55 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/RomanosTrechlis/blog-gen/util/logger"
+)
+
+func TestTextLogger(t *testing.T) {
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	l, err := logger.New(out, errOut, logger.Info, logger.TextFormat)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	logger.Debugf(l, "hidden")
+	logger.Infof(l, "Generating %s...", "Site")
+	l.Log(logger.Info, "Finished", logger.Fields{"pages": 3, "duration": time.Second})
+	logger.Warnf(l, "no front matter")
+
+	expected := "Generating Site...\nFinished duration=1s pages=3\n"
+	if out.String() != expected {
+		t.Errorf("expected '%s', got '%s'", expected, out.String())
+	}
+	if errOut.String() != "warning: no front matter\n" {
+		t.Errorf("expected the warning, got '%s'", errOut.String())
+	}
+}
+
+func TestJSONLogger(t *testing.T) {
+	out := &bytes.Buffer{}
+	l, err := logger.New(out, out, logger.Debug, logger.JSONFormat)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	l.Log(logger.Debug, "Finished", logger.Fields{"pages": 3, "duration": time.Second, "msg": "ignored"})
+
+	var entry map[string]interface{}
+	err = json.Unmarshal(out.Bytes(), &entry)
+	if err != nil {
+		t.Fatalf("expected a json line, got '%s': %v", out.String(), err)
+	}
+	if entry["level"] != "debug" || entry["msg"] != "Finished" || entry["pages"] != 3.0 || entry["duration"] != "1s" {
+		t.Errorf("expected the fields of the message, got %v", entry)
+	}
+}
+
+func TestNewFormat(t *testing.T) {
+	_, err := logger.New(nil, nil, logger.Info, "xml")
+	if err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
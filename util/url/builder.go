@@ -0,0 +1,73 @@
+package url
+
+import (
+	"fmt"
+	neturl "net/url"
+	"path"
+	"strings"
+)
+
+// Builder creates the urls of the pages of a site that may be hosted
+// under a path, e.g. https://example.com/blog/.
+type Builder struct {
+	base *neturl.URL
+}
+
+// NewBuilder creates a Builder for the site at blogURL
+func NewBuilder(blogURL string) (*Builder, error) {
+	u, err := neturl.Parse(blogURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing url %s: %v", blogURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("url %s must have a scheme and a host", blogURL)
+	}
+	base := *u
+	base.Path = "/" + strings.Trim(u.Path, "/")
+	if base.Path != "/" {
+		base.Path += "/"
+	}
+	base.RawPath = ""
+	base.RawQuery = ""
+	base.Fragment = ""
+	return &Builder{base: &base}, nil
+}
+
+// BasePath returns the path the site is hosted under. It starts and ends
+// with a slash.
+func (b *Builder) BasePath() string {
+	return b.base.Path
+}
+
+// RelURL returns the url of the site relative link, starting from the
+// root of the host, e.g. /blog/tags/go/ for /tags/go/. Links with a
+// scheme, protocol relative links and fragments are returned unchanged.
+func (b *Builder) RelURL(link string) string {
+	if isExternal(link) {
+		return link
+	}
+	joined := path.Join(b.base.Path, link)
+	if strings.HasSuffix(link, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+	return joined
+}
+
+// AbsURL returns the full url of the site relative link, e.g.
+// https://example.com/blog/tags/go/ for /tags/go/
+func (b *Builder) AbsURL(link string) string {
+	if isExternal(link) {
+		return link
+	}
+	u := *b.base
+	u.Path = b.RelURL(link)
+	return u.String()
+}
+
+func isExternal(link string) bool {
+	if strings.HasPrefix(link, "//") || strings.HasPrefix(link, "#") {
+		return true
+	}
+	u, err := neturl.Parse(link)
+	return err == nil && u.Scheme != ""
+}
@@ -2,8 +2,29 @@ package url
 
 import (
 	"strings"
+	"unicode"
 )
 
 func ChangePathToUrl(path string) string {
 	return strings.Replace(path, "\\", "/", -1)
 }
+
+// Slugify turns s into a lowercase string that can be used in a url.
+// Letters and digits are kept and every other run of characters
+// becomes a single dash.
+func Slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			dash = false
+			b.WriteRune(r)
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
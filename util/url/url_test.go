@@ -22,3 +22,56 @@ func TestChangePathToUrl(t *testing.T) {
 		}
 	}
 }
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		title, slug string
+	}{
+		{"Hello World", "hello-world"},
+		{"  Go: the  good parts! ", "go-the-good-parts"},
+		{"C++ & Go", "c-go"},
+		{"Καλημέρα Κόσμε", "καλημέρα-κόσμε"},
+		{"2020 review", "2020-review"},
+		{"---", ""},
+	}
+
+	for _, tt := range tests {
+		s := url.Slugify(tt.title)
+		if s != tt.slug {
+			t.Errorf("expected '%s', got '%s'", tt.slug, s)
+		}
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	tests := []struct {
+		blogURL, link, rel, abs string
+	}{
+		{"https://example.com", "/", "/", "https://example.com/"},
+		{"https://example.com/", "/tags/go/", "/tags/go/", "https://example.com/tags/go/"},
+		{"https://example.com/blog", "/", "/blog/", "https://example.com/blog/"},
+		{"https://example.com/blog/", "/tags/go/", "/blog/tags/go/", "https://example.com/blog/tags/go/"},
+		{"https://example.com/blog/", "style.css", "/blog/style.css", "https://example.com/blog/style.css"},
+		{"https://example.com/blog/", "/post.html", "/blog/post.html", "https://example.com/blog/post.html"},
+		{"https://example.com/blog/", "https://go.dev/", "https://go.dev/", "https://go.dev/"},
+		{"https://example.com/blog/", "#top", "#top", "#top"},
+	}
+
+	for _, tt := range tests {
+		b, err := url.NewBuilder(tt.blogURL)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if rel := b.RelURL(tt.link); rel != tt.rel {
+			t.Errorf("%s %s: expected '%s', got '%s'", tt.blogURL, tt.link, tt.rel, rel)
+		}
+		if abs := b.AbsURL(tt.link); abs != tt.abs {
+			t.Errorf("%s %s: expected '%s', got '%s'", tt.blogURL, tt.link, tt.abs, abs)
+		}
+	}
+
+	_, err := url.NewBuilder("example.com/blog")
+	if err == nil {
+		t.Errorf("expected error for url without scheme")
+	}
+}